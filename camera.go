@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+
+	"main.go/level"
+)
+
+// How quickly the camera catches up with the players, higher is snappier
+const cameraSpeed = 4.
+
+// Part of the screen kept between the leader and the edge when the group
+// doesn't fit on one screen
+const cameraMargin = .2
+
+// camera decides which part of the level the window shows. A screen fits
+// blocksPerRow by blocksPerCollumn blocks, the camera only moves along the
+// sides where the level is bigger than that.
+type camera struct {
+	// Bottom left corner of the screen in the world
+	position pixel.Vec
+
+	// Level being followed and its finish tiles, in blocks
+	level    *level.Level
+	finishes []pixel.Vec
+}
+
+// follow moves the camera toward the players in snap. dt is the time since
+// the last frame, in seconds.
+func (c *camera) follow(snap *gameSnapshot, screen pixel.Rect, dt float64) {
+	blockSizeX, blockSizeY := blockSizeIn(screen)
+
+	target, ok := c.target(snap, screen, blockSizeX, blockSizeY)
+	if !ok {
+		target = c.position
+	}
+
+	// Jump straight to a new level instead of sliding over
+	if snap.level != c.level {
+		c.level = snap.level
+		c.findFinishes()
+		c.position = target
+	} else {
+		c.position = c.position.Add(target.Sub(c.position).Scaled(math.Min(1, cameraSpeed*dt)))
+	}
+
+	//* Stay inside the level
+	width, height := 0, 0
+	if len(snap.blockGrid) > 0 {
+		width, height = len(snap.blockGrid), len(snap.blockGrid[0])
+	}
+	c.position.X = clampCamera(c.position.X, float64(width)-blocksPerRow, blockSizeX)
+	c.position.Y = clampCamera(c.position.Y, float64(height)-blocksPerCollumn, blockSizeY)
+}
+
+// clampCamera keeps one coordinate of the camera between 0 and the blocks
+// that don't fit on the screen.
+func clampCamera(pos, extraBlocks, blockSize float64) float64 {
+	if extraBlocks <= 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(pos, extraBlocks*blockSize))
+}
+
+// target is where the camera wants to be. It centers on the group, and
+// when the group is too spread out it keeps the player closest to a finish
+// on the screen and lets the stragglers fall behind.
+func (c *camera) target(snap *gameSnapshot, screen pixel.Rect, blockSizeX, blockSizeY float64) (pixel.Vec, bool) {
+	var group pixel.Rect
+	leader := -1
+	leaderDistance := math.Inf(1)
+	for i, val := range snap.players {
+		if !val.connected || val.health <= 0 {
+			continue
+		}
+		pos := pixel.V(val.position.X, val.position.Y)
+		if leader == -1 {
+			group = pixel.R(pos.X, pos.Y, pos.X, pos.Y)
+		} else {
+			group = group.Union(pixel.R(pos.X, pos.Y, pos.X, pos.Y))
+		}
+		if d := c.finishDistance(pos, blockSizeX, blockSizeY); leader == -1 || d < leaderDistance {
+			leader, leaderDistance = i, d
+		}
+	}
+	if leader == -1 {
+		return pixel.ZV, false
+	}
+
+	center := group.Center()
+	leaderPos := pixel.V(snap.players[leader].position.X, snap.players[leader].position.Y)
+	reachX := screen.W() * (.5 - cameraMargin)
+	reachY := screen.H() * (.5 - cameraMargin)
+	center.X = math.Max(leaderPos.X-reachX, math.Min(center.X, leaderPos.X+reachX))
+	center.Y = math.Max(leaderPos.Y-reachY, math.Min(center.Y, leaderPos.Y+reachY))
+
+	return center.Sub(screen.Size().Scaled(.5)), true
+}
+
+// finishDistance is how far pos is from the closest finish tile, in world
+// pixels.
+func (c *camera) finishDistance(pos pixel.Vec, blockSizeX, blockSizeY float64) float64 {
+	closest := math.Inf(1)
+	for _, val := range c.finishes {
+		closest = math.Min(closest, pixel.V((val.X+.5)*blockSizeX, (val.Y+.5)*blockSizeY).Sub(pos).Len())
+	}
+	return closest
+}
+
+func (c *camera) findFinishes() {
+	c.finishes = nil
+	if c.level == nil {
+		return
+	}
+	for x := 0; x < c.level.Width(); x++ {
+		for y := 0; y < c.level.Height(); y++ {
+			if isFinish(c.level.Tile(x, y)) {
+				c.finishes = append(c.finishes, pixel.V(float64(x), float64(y)))
+			}
+		}
+	}
+}
+
+// visibleBlocks is the range of blocks on the screen when its bottom left
+// corner is at offset, the end is exclusive.
+func visibleBlocks(grid [][]block, offset pixel.Vec, screen pixel.Rect) (minX, minY, maxX, maxY int) {
+	if len(grid) == 0 {
+		return 0, 0, 0, 0
+	}
+	blockSizeX, blockSizeY := blockSizeIn(screen)
+	minX = int(math.Max(0, math.Floor(offset.X/blockSizeX)))
+	minY = int(math.Max(0, math.Floor(offset.Y/blockSizeY)))
+	maxX = int(math.Min(float64(len(grid)), math.Ceil((offset.X+screen.W())/blockSizeX)))
+	maxY = int(math.Min(float64(len(grid[0])), math.Ceil((offset.Y+screen.H())/blockSizeY)))
+	return minX, minY, maxX, maxY
+}
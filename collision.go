@@ -0,0 +1,242 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+// Size of the part of a goober that bumps into blocks, in blocks. It is a
+// bit smaller than the sprite so goobers fit through gaps one block wide,
+// and its bottom is the bottom of the sprite.
+const playerHitboxWidth = .7
+const playerHitboxHeight = .9
+
+// Space kept between goobers and the blocks they touch, in world pixels, so
+// a goober standing next to a wall is never inside of it
+const collisionMargin = .01
+
+// collision is what stopped a goober while it moved.
+type collision struct {
+	wall    bool
+	ceiling bool
+	landed  bool
+}
+
+// hitbox is the box of a goober standing at pos, in world pixels.
+func hitbox(pos struct{ X, Y float64 }) pixel.Rect {
+	blockSizeX, blockSizeY := blockSize()
+	bottom := pos.Y - blockSizeY/2
+	return pixel.R(pos.X-playerHitboxWidth*blockSizeX/2, bottom, pos.X+playerHitboxWidth*blockSizeX/2, bottom+playerHitboxHeight*blockSizeY)
+}
+
+// movePlayer moves player i by dx, dy, stopping at the first block in the
+// way.
+func movePlayer(i int, dx, dy float64) collision {
+	return moveGoober(&players[i], blockGrid, dx, dy)
+}
+
+// moveGoober is movePlayer for any goober in any grid.
+func moveGoober(p *player, grid [][]block, dx, dy float64) collision {
+	moved, hit := moveBox(grid, hitbox(p.position), dx, dy)
+	p.position.X += moved.X
+	p.position.Y += moved.Y
+	return hit
+}
+
+// moveBox moves box by dx, dy through grid, sideways first and then up or
+// down, and returns how far it got. Each move stops at the first block in
+// the way, so fast things can't skip over thin walls.
+func moveBox(grid [][]block, box pixel.Rect, dx, dy float64) (pixel.Vec, collision) {
+	var hit collision
+	blockSizeX, blockSizeY := blockSize()
+
+	//* Sideways, the sides of the level are walls
+	if dx != 0 {
+		minY, maxY := cellSpan(box.Min.Y, box.Max.Y, blockSizeY)
+		blocked := func(x int) bool {
+			if len(grid) > 0 && (x < 0 || x >= len(grid)) {
+				return true
+			}
+			return anyInColumn(grid, x, minY, maxY, isWall)
+		}
+		edge := box.Max.X
+		if dx < 0 {
+			edge = box.Min.X
+		}
+		dx, hit.wall = sweep(edge, dx, blockSizeX, blocked)
+		box = box.Moved(pixel.V(dx, 0))
+	}
+
+	//* Up or down
+	if dy != 0 {
+		minX, maxX := cellSpan(box.Min.X, box.Max.X, blockSizeX)
+		if dy > 0 {
+			dy, hit.ceiling = sweep(box.Max.Y, dy, blockSizeY, func(y int) bool {
+				return anyInRow(grid, y, minX, maxX, isWall)
+			})
+		} else {
+			// Only tops the feet go through count, so goobers that jumped
+			// into a one way tile fall back out of it
+			dy, hit.landed = sweep(box.Min.Y, dy, blockSizeY, func(y int) bool {
+				return anyInRow(grid, y, minX, maxX, isSolid)
+			})
+		}
+
+		// Nothing falls through the floor under the level
+		if floor := bottomFloor - blockSizeY/2; box.Min.Y+dy < floor {
+			dy = floor - box.Min.Y
+			hit.landed = true
+		}
+	}
+
+	return pixel.V(dx, dy), hit
+}
+
+// sweep moves the side of a box at edge by d along one axis of the grid,
+// stopping it in front of the first line of cells blocked reports. It
+// returns how far the side got and whether something stopped it.
+func sweep(edge, d, size float64, blocked func(int) bool) (float64, bool) {
+	if math.IsNaN(d) || math.IsInf(d, 0) {
+		return 0, true
+	}
+	if d > 0 {
+		for c := int(math.Ceil((edge - collisionMargin) / size)); float64(c)*size <= edge+d; c++ {
+			if blocked(c) {
+				return math.Max(0, float64(c)*size-collisionMargin-edge), true
+			}
+		}
+		return d, false
+	}
+	for c := int(math.Floor((edge+collisionMargin)/size)) - 1; float64(c+1)*size >= edge+d; c-- {
+		if blocked(c) {
+			return math.Min(0, float64(c+1)*size+collisionMargin-edge), true
+		}
+	}
+	return d, false
+}
+
+// cellSpan is the first and last cell a box covers from lo to hi along one
+// axis. Cells it only touches with its sides don't count.
+func cellSpan(lo, hi, size float64) (int, int) {
+	return int(math.Floor((lo + collisionMargin) / size)), int(math.Floor((hi - collisionMargin) / size))
+}
+
+func anyInColumn(grid [][]block, x, minY, maxY int, match func(block) bool) bool {
+	for y := minY; y <= maxY; y++ {
+		if match(blockIn(grid, x, y)) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyInRow(grid [][]block, y, minX, maxX int, match func(block) bool) bool {
+	for x := minX; x <= maxX; x++ {
+		if match(blockIn(grid, x, y)) {
+			return true
+		}
+	}
+	return false
+}
+
+// groundUnder finds what p stands on in grid, x and y are the tile. The
+// floor under the level holds goobers up without a tile, so t is nil there.
+func groundUnder(p *player, grid [][]block) (t *tileType, x, y int, ok bool) {
+	blockSizeX, blockSizeY := blockSize()
+	box := hitbox(p.position)
+	if box.Min.Y <= bottomFloor-blockSizeY/2+collisionMargin*2 {
+		ok = true
+	}
+
+	y = int(math.Floor((box.Min.Y - collisionMargin*2) / blockSizeY))
+	top := float64(y+1) * blockSizeY
+	minX, maxX := cellSpan(box.Min.X, box.Max.X, blockSizeX)
+
+	// The tile under the middle comes first, a goober on the edge of some
+	// lava only burns once its middle is over it
+	middle := int(math.Floor(p.position.X / blockSizeX))
+	columns := []int{middle}
+	for x := minX; x <= maxX; x++ {
+		if x != middle {
+			columns = append(columns, x)
+		}
+	}
+	for _, x := range columns {
+		t := tileOf(blockIn(grid, x, y))
+		if t == nil || !t.Solid {
+			continue
+		}
+		// One way tiles only hold goobers whose feet are on top of them
+		if t.OneWay && box.Min.Y < top {
+			continue
+		}
+		return t, x, y, true
+	}
+	return nil, 0, 0, ok
+}
+
+// bumpingOn reports whether goobers bump into each other in the current
+// level.
+func bumpingOn() bool {
+	if currentLevel != nil && currentLevel.Bumping != nil {
+		return *currentLevel.Bumping
+	}
+	return bumping
+}
+
+// bumpHandler pushes overlapping goobers apart. A goober falling on the head
+// of another one bounces off and stuns it.
+func bumpHandler(deltaTime float64) {
+	for i := range players {
+		if players[i].stunLeft > 0 {
+			players[i].stunLeft -= time.Duration(deltaTime * float64(time.Second))
+		}
+	}
+	if !bumpingOn() {
+		return
+	}
+
+	for i := range players {
+		for j := i + 1; j < len(players); j++ {
+			if !bumpable(i) || !bumpable(j) {
+				continue
+			}
+			a, b := hitbox(players[i].position), hitbox(players[j].position)
+			overlap := a.Intersect(b)
+			if overlap.W() <= 0 || overlap.H() <= 0 {
+				continue
+			}
+
+			//* Stomping
+			// The one on top has its feet above the middle of the other one
+			// and is coming down on it
+			top, bottom := i, j
+			if a.Min.Y < b.Min.Y {
+				top, bottom = j, i
+			}
+			topBox, bottomBox := hitbox(players[top].position), hitbox(players[bottom].position)
+			if topBox.Min.Y > bottomBox.Center().Y && players[top].velocity.Y < players[bottom].velocity.Y {
+				movePlayer(top, 0, bottomBox.Max.Y-topBox.Min.Y+collisionMargin)
+				players[top].velocity.Y = stompBounce
+				players[top].grounded = false
+				players[top].coyoteLeft = 0
+				players[bottom].stunLeft = stunDuration
+				continue
+			}
+
+			//* Pushing, each goes half of the way
+			push := overlap.W()/2 + collisionMargin
+			if a.Center().X < b.Center().X {
+				push = -push
+			}
+			movePlayer(i, push, 0)
+			movePlayer(j, -push, 0)
+		}
+	}
+}
+
+func bumpable(i int) bool {
+	return players[i].connected && players[i].health > 0
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+
+	"main.go/level"
+)
+
+// testWorld makes l the level being played, with no goobers in it yet.
+func testWorld(t *testing.T, l *level.Level) {
+	loadTiles()
+	applyLevel(l)
+	players = nil
+	explosives = nil
+	t.Cleanup(func() {
+		players = nil
+		explosives = nil
+		particles = nil
+		blockGrid = nil
+	})
+}
+
+// testGoober is a goober standing with its feet on the bottom of block x, y.
+func testGoober(x, y float64) player {
+	blockSizeX, blockSizeY := blockSize()
+	p := newPlayer("test", 1, 1, newController(nil))
+	p.position.X, p.position.Y = x*blockSizeX, (y+.5)*blockSizeY
+	p.velocity.X, p.velocity.Y = 0, 0
+	p.grounded = false
+	return p
+}
+
+// step runs the physics of the goobers for ticks fixed ticks.
+func step(ticks int) {
+	for tick := 0; tick < ticks; tick++ {
+		gravityHandler(tickDeltaTime)
+		movementHandler(tickDeltaTime)
+		bumpHandler(tickDeltaTime)
+	}
+}
+
+func TestLanding(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 20; x++ {
+		l.SetTile(x, 4, level.Basic)
+	}
+	testWorld(t, l)
+	_, blockSizeY := blockSize()
+
+	// Falling far in one move still stops on top of the thin floor
+	players = []player{testGoober(5.5, 9)}
+	if hit := movePlayer(0, 0, -8*blockSizeY); !hit.landed {
+		t.Errorf("went through the floor")
+	}
+	if feet := hitbox(players[0].position).Min.Y; feet < 5*blockSizeY || feet > 5*blockSizeY+1 {
+		t.Errorf("feet at %.2f blocks, want 5", feet/blockSizeY)
+	}
+
+	// And so does falling for a while
+	players = []player{testGoober(5.5, 9)}
+	step(tickRate)
+	if !players[0].grounded || players[0].velocity.Y != 0 {
+		t.Errorf("not standing after a second, velocity %.1f", players[0].velocity.Y)
+	}
+	if feet := hitbox(players[0].position).Min.Y; feet < 5*blockSizeY || feet > 5*blockSizeY+1 {
+		t.Errorf("feet at %.2f blocks, want 5", feet/blockSizeY)
+	}
+}
+
+func TestCeilingAndWalls(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 20; x++ {
+		l.SetTile(x, 8, level.Basic)
+	}
+	for y := 0; y < 8; y++ {
+		l.SetTile(10, y, level.Basic)
+	}
+	testWorld(t, l)
+	blockSizeX, blockSizeY := blockSize()
+
+	players = []player{testGoober(5.5, 5)}
+	if hit := movePlayer(0, 0, 10*blockSizeY); !hit.ceiling {
+		t.Errorf("went through the ceiling")
+	}
+	if head := hitbox(players[0].position).Max.Y; head > 8*blockSizeY {
+		t.Errorf("head at %.2f blocks, inside the ceiling", head/blockSizeY)
+	}
+
+	players = []player{testGoober(7.5, 5)}
+	if hit := movePlayer(0, 10*blockSizeX, 0); !hit.wall {
+		t.Errorf("went through the wall")
+	}
+	if side := hitbox(players[0].position).Max.X; side > 10*blockSizeX {
+		t.Errorf("side at %.2f blocks, inside the wall", side/blockSizeX)
+	}
+
+	// The sides of the level are walls too
+	if hit := movePlayer(0, -20*blockSizeX, 0); !hit.wall {
+		t.Errorf("left the level")
+	}
+	if side := hitbox(players[0].position).Min.X; side < 0 {
+		t.Errorf("side at %.2f blocks, outside the level", side/blockSizeX)
+	}
+}
+
+func TestWorldEdges(t *testing.T) {
+	testWorld(t, level.New(20, 12))
+
+	// Goobers outside the grid and going fast must not crash the physics
+	for _, pos := range [][2]float64{{-5, -5}, {-5, 30}, {50, 5}, {50, 50}, {0, 11.9}, {19.9, 0}} {
+		p := testGoober(pos[0], pos[1])
+		p.velocity.X, p.velocity.Y = globalTerminalVelocityX, -globalTerminalVelocityY
+		players = append(players, p)
+	}
+	step(tickRate * 2)
+}
+
+func TestStomp(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 20; x++ {
+		l.SetTile(x, 4, level.Basic)
+	}
+	testWorld(t, l)
+	saved := bumping
+	t.Cleanup(func() { bumping = saved })
+
+	// One goober falls on the head of another one
+	stomp := func() (player, player) {
+		players = []player{testGoober(5.5, 5), testGoober(5.6, 7)}
+		players[1].velocity.Y = -300
+		for tick := 0; tick < tickRate && players[0].stunLeft <= 0; tick++ {
+			step(1)
+		}
+		return players[1], players[0]
+	}
+
+	bumping = true
+	top, bottom := stomp()
+	if bottom.stunLeft <= 0 {
+		t.Fatal("the goober below was not stunned")
+	}
+	if top.velocity.Y <= 0 {
+		t.Errorf("the goober on top did not bounce off")
+	}
+	if hitbox(top.position).Min.Y < hitbox(bottom.position).Max.Y {
+		t.Errorf("the goober on top is inside the one below")
+	}
+
+	// Without bumping they go through each other
+	bumping = false
+	if _, bottom := stomp(); bottom.stunLeft > 0 {
+		t.Errorf("stunned with bumping off")
+	}
+}
+
+func TestPush(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 20; x++ {
+		l.SetTile(x, 4, level.Basic)
+	}
+	testWorld(t, l)
+	saved := bumping
+	bumping = true
+	t.Cleanup(func() { bumping = saved })
+
+	players = []player{testGoober(5.5, 5), testGoober(5.7, 5)}
+	step(1)
+	if overlap := hitbox(players[0].position).Intersect(hitbox(players[1].position)); overlap.W() > 0 && overlap.H() > 0 {
+		t.Errorf("goobers still overlap by %.2f pixels", overlap.W())
+	}
+	if players[0].position.X >= players[1].position.X {
+		t.Errorf("goobers swapped sides")
+	}
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Messages a controller can fall behind on before new ones get dropped
+const outboxSize = 32
+
+// controller is a phone connected over a websocket. Only its writer
+// goroutine writes to ws, everyone else queues messages with send or
+// sendState.
+type controller struct {
+	ws *websocket.Conn
+
+	outbox chan serverMessage
+	closed chan struct{}
+	// State updates waiting to be written, newest per key. A slow phone
+	// skips the updates it missed instead of queueing them all.
+	stateLock  sync.Mutex
+	state      map[string]serverMessage
+	stateOrder []string
+	stateReady chan struct{}
+
+	// Protocol version agreed on in hello, 0 until then. Only the reader
+	// goroutine uses it.
+	version int
+	// Still talks the old string protocol. The reader sets it and the
+	// writer reads it, so it is atomic.
+	legacy atomic.Bool
+
+	// Input collected since the last tick. Only the latest stick position
+	// is kept while button presses add up until the game takes them.
+	inputLock    sync.Mutex
+	input        playerInput
+	lastSequence uint16
+	gotSequence  bool
+}
+
+func newController(ws *websocket.Conn) *controller {
+	return &controller{
+		ws:         ws,
+		outbox:     make(chan serverMessage, outboxSize),
+		closed:     make(chan struct{}),
+		state:      map[string]serverMessage{},
+		stateReady: make(chan struct{}, 1),
+	}
+}
+
+func (c *controller) updateInput(change func(in *playerInput)) {
+	c.inputLock.Lock()
+	defer c.inputLock.Unlock()
+	change(&c.input)
+}
+
+// takeInput returns the input for this tick. The stick stays where it was
+// while buttons are only reported once.
+func (c *controller) takeInput() playerInput {
+	c.inputLock.Lock()
+	defer c.inputLock.Unlock()
+
+	in := c.input
+	c.input.jump = false
+	c.input.use = false
+	return in
+}
+
+// applyInputFrame merges a binary input frame, ignoring frames that arrive
+// after a newer one.
+func (c *controller) applyInputFrame(frame inputFrame) {
+	c.updateInput(func(in *playerInput) {
+		if c.gotSequence && int16(frame.sequence-c.lastSequence) <= 0 {
+			return
+		}
+		c.lastSequence = frame.sequence
+		c.gotSequence = true
+
+		// Phones count the stick down from the top of the screen
+		in.stickX = float64(frame.stickX)
+		in.stickY = -float64(frame.stickY)
+		in.jump = in.jump || frame.buttons&inputButtonGreen != 0
+		in.use = in.use || frame.buttons&inputButtonRed != 0
+	})
+}
+
+// send queues msg without waiting. If the phone is too far behind the
+// message is dropped.
+func (c *controller) send(msg serverMessage) {
+	select {
+	case c.outbox <- msg:
+	default:
+		fmt.Println("Controller is too slow, dropping message")
+	}
+}
+
+func (c *controller) closeAfterSending() {
+	c.send(closeMessage{})
+}
+
+// sendState queues msg replacing any message with the same key that was not
+// written yet.
+func (c *controller) sendState(key string, msg serverMessage) {
+	c.stateLock.Lock()
+	if _, ok := c.state[key]; !ok {
+		c.stateOrder = append(c.stateOrder, key)
+	}
+	c.state[key] = msg
+	c.stateLock.Unlock()
+
+	select {
+	case c.stateReady <- struct{}{}:
+	default:
+	}
+}
+
+func (c *controller) takeState() []serverMessage {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	var toSend []serverMessage
+	for _, key := range c.stateOrder {
+		toSend = append(toSend, c.state[key])
+	}
+	c.state = map[string]serverMessage{}
+	c.stateOrder = nil
+	return toSend
+}
+
+func (c *controller) write(msg serverMessage) error {
+	frames, err := encodeServerMessage(msg, c.legacy.Load())
+	if err != nil {
+		fmt.Println("Failed to encode message: ", err)
+		return nil
+	}
+	for _, frame := range frames {
+		c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.ws.WriteMessage(websocket.TextMessage, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLoop is the only goroutine writing to the websocket. It also pings
+// the phone so dead connections get noticed.
+func (c *controller) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-c.closed:
+			return
+		case msg := <-c.outbox:
+			if _, ok := msg.(closeMessage); ok {
+				c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+				c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				c.ws.Close()
+				return
+			}
+			err = c.write(msg)
+		case <-c.stateReady:
+			for _, msg := range c.takeState() {
+				if err = c.write(msg); err != nil {
+					break
+				}
+			}
+		case <-ticker.C:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			err = c.ws.WriteMessage(websocket.PingMessage, nil)
+		}
+
+		// The reader notices the closed connection and cleans up
+		if err != nil {
+			fmt.Println(err)
+			c.ws.Close()
+			return
+		}
+	}
+}
+
+func (c *controller) decode(data []byte) (clientMessage, error) {
+	// The first message tells us which protocol the controller speaks
+	if c.version == 0 && !c.legacy.Load() && (len(data) == 0 || data[0] != '{') {
+		c.legacy.Store(true)
+	}
+	if c.legacy.Load() {
+		return decodeLegacyMessage(data)
+	}
+	return decodeClientMessage(data)
+}
+
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	c := newController(conn)
+	defer close(c.closed)
+	go c.writeLoop()
+
+	fmt.Println("Client connected")
+
+	//* Phones that vanish without closing stop answering pings
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	// Session of the player on this connection, set by join or resume
+	var token string
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			fmt.Println(err)
+			conn.Close()
+			sendCommand(func() { disconnectPlayer(token, c) })
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		//* Stick updates come in as small binary frames
+		if kind == websocket.BinaryMessage {
+			if c.version < binaryInputVersion {
+				c.send(newErrorMessage(errBadMessage, errors.New("binary input needs a newer protocol version")))
+				continue
+			}
+			frame, err := decodeInputFrame(data)
+			if err != nil {
+				c.send(newErrorMessage(errBadMessage, err))
+				continue
+			}
+			c.applyInputFrame(frame)
+			continue
+		}
+
+		msg, err := c.decode(data)
+		if err != nil {
+			fmt.Println("Bad message from controller: ", err)
+			c.send(newErrorMessage(errBadMessage, err))
+			continue
+		}
+		token = websocketLogic(msg, c, token)
+	}
+}
+
+// disconnectPlayer marks the player away when its current connection drops.
+// It keeps its place for reconnectGracePeriod in case the controller comes
+// back with resume.
+func disconnectPlayer(token string, c *controller) {
+	p := playerByToken(token)
+	if p == nil || p.conn != c {
+		return
+	}
+
+	p.connected = false
+	p.disconnectedAt = time.Now()
+	rosterChanged = true
+	fmt.Println("Player disconnected: ", p.playerName)
+}
+
+// removeAwayPlayers drops players that did not come back in time.
+func removeAwayPlayers() {
+	kept := players[:0]
+	for _, val := range players {
+		if !val.connected && time.Since(val.disconnectedAt) >= reconnectGracePeriod {
+			fmt.Println("Player left: ", val.playerName)
+			rosterChanged = true
+			continue
+		}
+		kept = append(kept, val)
+	}
+	players = kept
+}
+
+func connectedPlayers(list []player) int {
+	count := 0
+	for i := range list {
+		if list[i].connected {
+			count++
+		}
+	}
+	return count
+}
+
+func newPlayer(name string, hat, character int, c *controller) player {
+	return player{
+		hatID:       hat,
+		characterID: character,
+		wearingHat:  true,
+		playerName:  name,
+		animation:   "idle",
+		token:       newSessionToken(),
+		conn:        c,
+		connected:   true,
+		winner:      false,
+		score:       0,
+		position:    struct{ X, Y float64 }{0.0, windowY},
+		velocity:    struct{ X, Y float64 }{0.0, -100.0},
+		terminalVelocity: struct {
+			X float64
+			Y float64
+		}{globalTerminalVelocityX, globalTerminalVelocityY},
+		grounded:     true,
+		jumpPower:    globalJumpPower,
+		speed:        gloablSpeed,
+		inventory:    [itemKinds]int{itemBomb: minBombsLeft},
+		health:       100,
+		claimedItems: []struct{ X, Y int }{},
+	}
+}
+
+func newSessionToken() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// websocketLogic handles a decoded controller message on the connection's
+// goroutine and hands the result to the game goroutine as a command. It
+// returns the session token the connection belongs to after the message.
+func websocketLogic(msg clientMessage, c *controller, token string) string {
+	//* Agree on a protocol version first
+	if msg.Type == msgHello {
+		version := msg.Version
+		if version > protocolVersion {
+			version = protocolVersion
+		}
+		if version < minProtocolVersion {
+			c.send(newErrorMessage(errBadVersion, fmt.Errorf("protocol version %d is not supported", msg.Version)))
+			c.closeAfterSending()
+			return token
+		}
+		c.version = version
+		c.send(newWelcomeMessage(version))
+		return token
+	}
+	if c.version == 0 && !c.legacy.Load() {
+		c.send(newErrorMessage(errNoHello, errors.New("say hello first")))
+		return token
+	}
+
+	switch msg.Type {
+	// Rebind a player that lost its connection
+	case msgResume:
+		// Wait for the lookup, an unknown token must not unlock the other messages
+		found := false
+		queryGame(func() {
+			p := playerByToken(msg.Token)
+			if p == nil {
+				c.send(newErrorMessage(errUnknownSession, errors.New("unknown session")))
+				return
+			}
+			if old := p.conn; old != c && old != nil && old.ws != nil {
+				old.ws.Close()
+			}
+			p.conn = c
+			p.connected = true
+			rosterChanged = true
+			c.send(newSessionMessage(msg.Token, p.id))
+			c.sendState(msgState, newGameStateMessage())
+			fmt.Println("Player resumed: ", p.playerName)
+			found = true
+		})
+		if found {
+			token = msg.Token
+		}
+
+	// Add new players
+	case msgJoin:
+		// One goober per connection, a second join would orphan the first
+		if token != "" {
+			c.send(newErrorMessage(errBadMessage, errors.New("already joined")))
+			break
+		}
+		newPlayer := newPlayer(msg.Name, msg.Hat, msg.Character, c)
+		token = newPlayer.token
+		sendCommand(func() {
+			newPlayer.id = nextPlayerID
+			nextPlayerID++
+			if currentState == stateTestPlay {
+				benchedPlayers = append(benchedPlayers, newPlayer)
+			} else {
+				players = append(players, newPlayer)
+			}
+			rosterChanged = true
+			c.send(newSessionMessage(newPlayer.token, newPlayer.id))
+			c.sendState(msgState, newGameStateMessage())
+			fmt.Println("New player: ", newPlayer.playerName)
+		})
+
+	// Check movements
+	case msgStick:
+		if token == "" {
+			break
+		}
+		c.updateInput(func(in *playerInput) {
+			in.stickX = msg.X
+			in.stickY = -msg.Y
+		})
+
+	// Check jumps and items
+	case msgButton:
+		if token == "" {
+			break
+		}
+		if msg.Button == buttonGreen {
+			c.updateInput(func(in *playerInput) { in.jump = true })
+		} else {
+			c.updateInput(func(in *playerInput) { in.use = true })
+		}
+
+	case msgAnswer:
+		if token == "" {
+			break
+		}
+		answer := msg.Answer
+		sendCommand(func() {
+			playerID := findPlayerByToken(token)
+			if playerID == -1 || !inLevel() {
+				return
+			}
+			if answer == triviaAnswer {
+				players[playerID].inventory[itemBomb] += 1
+				players[playerID].score += correctAnswerPoints
+			}
+		})
+
+	case msgItem:
+		if token == "" {
+			break
+		}
+		kind, _ := itemByName(msg.Item)
+		sendCommand(func() {
+			if playerID := findPlayerByToken(token); playerID != -1 {
+				players[playerID].selectedItem = kind
+			}
+		})
+
+	case msgReady, msgHost, msgStart, msgKick, msgPack, msgPlaylist, msgBumping, msgPause:
+		if token == "" {
+			break
+		}
+		sendCommand(func() { lobbyCommand(token, msg) })
+	}
+
+	return token
+}
+
+// broadcast queues msg for every connected player. It never waits for a
+// phone so it is safe to call from the game goroutine.
+func broadcast(msg serverMessage) {
+	for i := range players {
+		if players[i].connected && players[i].conn.ws != nil {
+			players[i].conn.send(msg)
+		}
+	}
+}
+
+// broadcastState is broadcast for state updates that replace each other.
+func broadcastState(key string, msg serverMessage) {
+	for i := range players {
+		if players[i].connected && players[i].conn.ws != nil {
+			players[i].conn.sendState(key, msg)
+		}
+	}
+}
+
+// notifyControllers pushes items and health to every phone.
+func notifyControllers() {
+	for i := range players {
+		if players[i].connected && players[i].conn.ws != nil {
+			players[i].conn.sendState(msgStatus, newStatusMessage(players[i]))
+		}
+	}
+}
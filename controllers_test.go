@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+// runGame runs queued commands like the game goroutine would until the test
+// is over.
+func runGame(t *testing.T) {
+	players = nil
+	nextPlayerID = 0
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case change := <-commands:
+				change()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+		players = nil
+	})
+}
+
+// sentErrors returns the error codes c got so far.
+func sentErrors(c *controller) []string {
+	var codes []string
+	for {
+		select {
+		case msg := <-c.outbox:
+			if e, ok := msg.(errorMessage); ok {
+				codes = append(codes, e.Code)
+			}
+		default:
+			return codes
+		}
+	}
+}
+
+func TestJoinTwice(t *testing.T) {
+	runGame(t)
+	c := newController(nil)
+	c.version = protocolVersion
+
+	join := clientMessage{Type: msgJoin, Name: "bob", Hat: 1, Character: 1}
+	token := websocketLogic(join, c, "")
+	if token == "" {
+		t.Fatal("join did not hand out a token")
+	}
+	if again := websocketLogic(join, c, token); again != token {
+		t.Errorf("second join changed the token")
+	}
+	// Wait for the joins to reach the game
+	queryGame(func() {})
+
+	if len(players) != 1 {
+		t.Errorf("got %d players, want 1", len(players))
+	}
+	if codes := sentErrors(c); len(codes) != 1 || codes[0] != errBadMessage {
+		t.Errorf("got errors %v, want one %q", codes, errBadMessage)
+	}
+}
+
+func TestResume(t *testing.T) {
+	runGame(t)
+	old := newController(nil)
+	old.version = protocolVersion
+	token := websocketLogic(clientMessage{Type: msgJoin, Name: "bob", Hat: 1, Character: 1}, old, "")
+
+	c := newController(nil)
+	c.version = protocolVersion
+	if got := websocketLogic(clientMessage{Type: msgResume, Token: "nope"}, c, ""); got != "" {
+		t.Errorf("unknown session handed out token %q", got)
+	}
+	if codes := sentErrors(c); len(codes) != 1 || codes[0] != errUnknownSession {
+		t.Errorf("got errors %v, want one %q", codes, errUnknownSession)
+	}
+
+	// The old controller has no websocket to close
+	if got := websocketLogic(clientMessage{Type: msgResume, Token: token}, c, ""); got != token {
+		t.Errorf("resume got token %q, want %q", got, token)
+	}
+	queryGame(func() {
+		if len(players) != 1 || players[0].conn != c {
+			t.Errorf("player was not moved to the new controller")
+		}
+	})
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+
+	"main.go/level"
+	"main.go/levelcheck"
+)
+
+type editorTile struct {
+	letter byte
+	name   string
+}
+
+// Keys for the first tiles, the rest are picked with Tab
+var tileKeys = []pixelgl.Button{pixelgl.Key1, pixelgl.Key2, pixelgl.Key3, pixelgl.Key4, pixelgl.Key5, pixelgl.Key6, pixelgl.Key7, pixelgl.Key8, pixelgl.Key9}
+
+// editorTiles are the tiles the editor paints. The eraser comes last and is
+// picked with 0.
+func editorTiles() []editorTile {
+	var tiles []editorTile
+	for _, val := range tileTypes {
+		tiles = append(tiles, editorTile{val.Letter[0], val.Name})
+	}
+	return append(tiles, editorTile{level.Empty, "eraser"})
+}
+
+const editorHelp = "1-9/Tab tile  0 eraser  right click erase  S spawn  Shift+S add spawn  Up/Down time  PgUp/PgDn level  N new  T test  Ctrl+S save  Esc leave\n" +
+	"Left/Right/wheel scroll  Shift+Up/Down scroll  Ctrl+arrows resize"
+
+// levelEditor is opened from the lobby with E. It lives on the window
+// goroutine and only hands copies of the level to the game goroutine.
+type levelEditor struct {
+	pack   *level.Pack
+	levels []string
+	rules  levelcheck.Rules
+	tiles  []editorTile
+
+	// Level being edited, file is empty until a new level is saved
+	level *level.Level
+	file  string
+
+	tile    int
+	changed bool
+	message string
+
+	// Set by the first press of a key that would lose the changes, the
+	// second press goes ahead
+	confirmDiscard bool
+
+	// Blocks scrolled away to the left and to the bottom
+	scrollX, scrollY int
+}
+
+// newLevelEditor must run on the game goroutine, it reads the pack and
+// measures the jumps for the checker.
+func newLevelEditor(p *level.Pack) *levelEditor {
+	e := &levelEditor{
+		pack:   p,
+		levels: levelFiles(p),
+		rules:  levelRules(),
+		tiles:  editorTiles(),
+	}
+	e.open(0)
+	return e
+}
+
+func (e *levelEditor) index() int {
+	for i, val := range e.levels {
+		if val == e.file {
+			return i
+		}
+	}
+	return len(e.levels)
+}
+
+func (e *levelEditor) open(i int) {
+	if i < 0 || i >= len(e.levels) {
+		e.newLevel()
+		return
+	}
+	l, err := e.pack.Load(e.levels[i])
+	if err != nil {
+		e.message = err.Error()
+		e.newLevel()
+		return
+	}
+	e.level = l
+	e.file = e.levels[i]
+	e.changed = false
+	e.confirmDiscard = false
+	e.scrollX, e.scrollY = 0, 0
+	e.message = "Opened " + e.file
+}
+
+func (e *levelEditor) newLevel() {
+	e.level = level.New(blocksPerRow, blocksPerCollumn)
+	e.level.Name = fmt.Sprint("Level ", len(e.levels)+1)
+	e.file = ""
+	e.changed = false
+	e.confirmDiscard = false
+	e.scrollX, e.scrollY = 0, 0
+}
+
+// nextFile is the first free numbered file name in the pack.
+func (e *levelEditor) nextFile() string {
+	next := 0
+	for _, val := range e.levels {
+		if n, err := strconv.Atoi(strings.TrimSuffix(val, level.Ext)); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return fmt.Sprint(next) + level.Ext
+}
+
+func (e *levelEditor) save() {
+	isNew := e.file == ""
+	if isNew {
+		e.file = e.nextFile()
+	}
+	if err := e.level.Save(path.Join(e.pack.Dir, e.file)); err != nil {
+		e.message = "Failed to save: " + err.Error()
+		return
+	}
+	e.changed = false
+
+	if isNew {
+		e.levels = append(e.levels, e.file)
+		pack, file := e.pack, e.file
+		sendCommand(func() {
+			if err := addLevelToPack(pack, file); err != nil {
+				fmt.Println("Failed to add level to pack: ", err)
+			}
+		})
+	}
+
+	e.message = "Saved " + e.file
+	if problems := levelcheck.Check(e.level, e.rules); len(problems) > 0 {
+		e.message += fmt.Sprintf(", %d problems, first: %s", len(problems), problems[0])
+	}
+}
+
+// cell is the tile under the mouse.
+func (e *levelEditor) cell(win *pixelgl.Window) (int, int) {
+	blockSizeX, blockSizeY := blockSizeIn(win.Bounds())
+	pos := win.MousePosition()
+	return int(math.Floor(pos.X/blockSizeX)) + e.scrollX, int(math.Floor(pos.Y/blockSizeY)) + e.scrollY
+}
+
+// offset is where the bottom left corner of the window is in the level.
+func (e *levelEditor) offset(win *pixelgl.Window) pixel.Vec {
+	blockSizeX, blockSizeY := blockSizeIn(win.Bounds())
+	return pixel.V(float64(e.scrollX)*blockSizeX, float64(e.scrollY)*blockSizeY)
+}
+
+// scroll moves the view by dx, dy blocks without leaving the level.
+func (e *levelEditor) scroll(dx, dy int) {
+	e.scrollX += dx
+	e.scrollY += dy
+	if maxX := e.level.Width() - blocksPerRow; e.scrollX > maxX {
+		e.scrollX = maxX
+	}
+	if maxY := e.level.Height() - blocksPerCollumn; e.scrollY > maxY {
+		e.scrollY = maxY
+	}
+	if e.scrollX < 0 {
+		e.scrollX = 0
+	}
+	if e.scrollY < 0 {
+		e.scrollY = 0
+	}
+}
+
+func (e *levelEditor) markChanged() {
+	e.changed = true
+	e.confirmDiscard = false
+}
+
+// discard reports whether the level can be left, asking for a second press
+// if that would lose changes.
+func (e *levelEditor) discard() bool {
+	if !e.changed || e.confirmDiscard {
+		return true
+	}
+	e.confirmDiscard = true
+	e.message = "Unsaved changes, press again to throw them away"
+	return false
+}
+
+// switchLevel opens another level once the changes may be lost.
+func (e *levelEditor) switchLevel(i int) {
+	if e.discard() {
+		e.open(i)
+	}
+}
+
+// update handles the keys and the mouse for one frame.
+func (e *levelEditor) update(win *pixelgl.Window) {
+	x, y := e.cell(win)
+	inside := x >= 0 && x < e.level.Width() && y >= 0 && y < e.level.Height()
+
+	//* Painting
+	for i, key := range tileKeys {
+		if i < len(e.tiles)-1 && win.JustPressed(key) {
+			e.tile = i
+		}
+	}
+	if win.JustPressed(pixelgl.Key0) {
+		e.tile = len(e.tiles) - 1
+	}
+	if win.JustPressed(pixelgl.KeyTab) {
+		e.tile = (e.tile + 1) % len(e.tiles)
+	}
+	if inside && win.Pressed(pixelgl.MouseButtonLeft) && e.level.Tile(x, y) != e.tiles[e.tile].letter {
+		e.level.SetTile(x, y, e.tiles[e.tile].letter)
+		e.markChanged()
+	}
+	if inside && win.Pressed(pixelgl.MouseButtonRight) && e.level.Tile(x, y) != level.Empty {
+		e.level.SetTile(x, y, level.Empty)
+		e.markChanged()
+	}
+
+	control := win.Pressed(pixelgl.KeyLeftControl) || win.Pressed(pixelgl.KeyRightControl)
+	shift := win.Pressed(pixelgl.KeyLeftShift) || win.Pressed(pixelgl.KeyRightShift)
+
+	//* Spawns
+	if inside && !control && win.JustPressed(pixelgl.KeyS) {
+		spawn := level.Spawn{X: float64(x) + .5, Y: float64(y) + .5}
+		if shift {
+			e.level.Spawns = append(e.level.Spawns, spawn)
+		} else {
+			e.level.Spawns = []level.Spawn{spawn}
+		}
+		e.markChanged()
+	}
+
+	//* Time limit
+	if !control && !shift && win.JustPressed(pixelgl.KeyUp) {
+		e.level.TimeLimit += 5
+		e.markChanged()
+	}
+	if !control && !shift && win.JustPressed(pixelgl.KeyDown) && e.level.TimeLimit > 5 {
+		e.level.TimeLimit -= 5
+		e.markChanged()
+	}
+
+	//* Scrolling and size
+	pressed := func(key pixelgl.Button) bool {
+		return win.JustPressed(key) || win.Repeated(key)
+	}
+	dx, dy := 0, 0
+	if pressed(pixelgl.KeyLeft) {
+		dx--
+	}
+	if pressed(pixelgl.KeyRight) {
+		dx++
+	}
+	if pressed(pixelgl.KeyDown) {
+		dy--
+	}
+	if pressed(pixelgl.KeyUp) {
+		dy++
+	}
+	switch {
+	case control && (dx != 0 || dy != 0):
+		e.level.Resize(e.level.Width()+dx, e.level.Height()+dy)
+		e.markChanged()
+		e.scroll(0, 0)
+	case shift:
+		e.scroll(0, dy)
+	default:
+		e.scroll(dx-int(win.MouseScroll().Y), 0)
+	}
+
+	//* Files
+	if control && win.JustPressed(pixelgl.KeyS) {
+		e.save()
+	}
+	if win.JustPressed(pixelgl.KeyPageUp) {
+		e.switchLevel(e.index() - 1)
+	}
+	if win.JustPressed(pixelgl.KeyPageDown) {
+		e.switchLevel(e.index() + 1)
+	}
+	if win.JustPressed(pixelgl.KeyN) {
+		e.switchLevel(len(e.levels))
+	}
+
+	//* Test play
+	if win.JustPressed(pixelgl.KeyT) {
+		testLevel := e.level.Clone()
+		sendCommand(func() { startTestPlay(testLevel) })
+	}
+}
+
+// grid turns the level into blocks for drawing.
+func (e *levelEditor) grid() [][]block {
+	grid := make([][]block, e.level.Width())
+	for x := range grid {
+		grid[x] = make([]block, e.level.Height())
+		for y := range grid[x] {
+			grid[x][y] = blockFor(e.level.Tile(x, y))
+		}
+	}
+	return grid
+}
+
+// spawnPositions are the spawns in window coordinates.
+func (e *levelEditor) spawnPositions(win *pixelgl.Window) []pixel.Vec {
+	blockSizeX, blockSizeY := blockSizeIn(win.Bounds())
+	var positions []pixel.Vec
+	for _, val := range e.level.Spawns {
+		positions = append(positions, pixel.V(val.X*blockSizeX, val.Y*blockSizeY).Sub(e.offset(win)))
+	}
+	return positions
+}
+
+func (e *levelEditor) status() string {
+	file := e.file
+	if file == "" {
+		file = "new level"
+	}
+	if e.changed {
+		file += "*"
+	}
+	return fmt.Sprintf("%s - %s (%s)  Size: %dx%d  Time: %.0fs  Tile: %s\n%s\n%s", e.level.Name, file, e.pack.Name, e.level.Width(), e.level.Height(), e.level.TimeLimit, e.tiles[e.tile].name, e.message, editorHelp)
+}
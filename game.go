@@ -0,0 +1,131 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"main.go/level"
+)
+
+// The game goroutine is the only one allowed to touch players, blockGrid,
+// particles and the level state. Everyone else sends it commands and reads
+// the snapshot it publishes after every tick.
+var commands = make(chan func(), 1024)
+
+// sendCommand runs change on the game goroutine without waiting for it.
+func sendCommand(change func()) {
+	commands <- change
+}
+
+// queryGame runs read on the game goroutine and waits until it is done.
+func queryGame(read func()) {
+	done := make(chan struct{})
+	commands <- func() {
+		read()
+		close(done)
+	}
+	<-done
+}
+
+// gameSnapshot is a copy of the game state that is safe to read from any
+// goroutine. It must never be modified.
+type gameSnapshot struct {
+	players     []player
+	blockGrid   [][]block
+	particles   []particle
+	explosives  []explosive
+	state       gameState
+	stateClock  time.Duration
+	paused      bool
+	packName    string
+	playlist    string
+	levelID     int
+	numOfLevels int
+
+	// Levels are never changed once loaded
+	level *level.Level
+
+	levelClock    time.Duration
+	levelDuration time.Duration
+}
+
+var snapshot atomic.Pointer[gameSnapshot]
+
+func currentSnapshot() *gameSnapshot {
+	if snap := snapshot.Load(); snap != nil {
+		return snap
+	}
+	return &gameSnapshot{}
+}
+
+func publishSnapshot() {
+	snap := &gameSnapshot{
+		players:       append([]player(nil), players...),
+		blockGrid:     make([][]block, len(blockGrid)),
+		particles:     append([]particle(nil), particles...),
+		explosives:    append([]explosive(nil), explosives...),
+		state:         currentState,
+		stateClock:    stateClock,
+		paused:        paused,
+		packName:      currentPack.Name,
+		playlist:      playlistName(),
+		levelID:       currentLevelID,
+		numOfLevels:   numOfLevels,
+		level:         currentLevel,
+		levelClock:    levelClock,
+		levelDuration: levelDuration,
+	}
+	for x := range blockGrid {
+		snap.blockGrid[x] = append([]block(nil), blockGrid[x]...)
+	}
+	snapshot.Store(snap)
+}
+
+func startGame() {
+	if currentState == stateLobby && connectedPlayers(players) > 0 {
+		// New shuffle for every game
+		buildPlaylist()
+		changeState(stateLevelIntro)
+	}
+}
+
+// gameLoop owns the game state. It runs commands as they arrive and steps
+// the simulation on a ticker.
+func gameLoop() {
+	ticker := time.NewTicker(tickDuration)
+	defer ticker.Stop()
+
+	lastTime := time.Now()
+	lastStatus := time.Now()
+	for {
+		select {
+		case change := <-commands:
+			change()
+
+		case now := <-ticker.C:
+			elapsed := now.Sub(lastTime)
+			lastTime = now
+
+			removeAwayPlayers()
+			updateState(elapsed)
+			sendRoster()
+
+			//* Keep the phones up to date
+			if inLevel() && now.Sub(lastStatus) >= statusInterval {
+				lastStatus = now
+				notifyControllers()
+			}
+
+			//* Forget old particles
+			alive := particles[:0]
+			for _, val := range particles {
+				if val.alive(levelClock) {
+					alive = append(alive, val)
+				}
+			}
+			particles = alive
+
+			publishSnapshot()
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// runHeadless runs the game without a window. The game starts from the
+// lobby once the host presses start or everyone is ready.
+func runHeadless() {
+	//* Stop on ctrl+c
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+
+	fmt.Println("Running headless, waiting for players...")
+	sendCommand(finishStory)
+
+	<-stop
+	queryGame(saveLogs)
+}
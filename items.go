@@ -0,0 +1,208 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/faiface/pixel"
+)
+
+// item is a kind of thing goobers carry, the red button uses the one they
+// picked.
+type item int
+
+const (
+	itemBomb item = iota
+	itemMine
+	itemBoost
+
+	itemKinds
+)
+
+// Names the controllers use, also the pictures in /assets/items/
+var itemNames = [itemKinds]string{"bomb", "mine", "boost"}
+
+func itemByName(name string) (item, bool) {
+	for i, val := range itemNames {
+		if val == name {
+			return item(i), true
+		}
+	}
+	return 0, false
+}
+
+// Speed thrown bombs leave the goober with, in world pixels per second
+const throwSpeed = 700.
+
+// Side of bombs and mines, in blocks
+const explosiveSize = .4
+
+// Time after a mine is put down before stepping on it sets it off, so the
+// goober that put it down can walk away
+const mineArmTime = time.Second
+
+// How long a speed boost lasts and how much faster goobers run with it
+const boostDuration = time.Second * 5
+const boostSpeed = 1.4
+
+// explosive is a thrown bomb, or a mine waiting for someone to step on it.
+type explosive struct {
+	kind     item
+	position pixel.Vec
+	velocity pixel.Vec
+
+	// Lit explosives go off once the fuse runs out
+	lit      bool
+	fuseLeft time.Duration
+
+	// Mines only go off once this runs out
+	armLeft time.Duration
+}
+
+// Bombs and mines in the level
+var explosives []explosive
+
+func explosiveBox(pos pixel.Vec) pixel.Rect {
+	blockSizeX, blockSizeY := blockSize()
+	halfX, halfY := explosiveSize*blockSizeX/2, explosiveSize*blockSizeY/2
+	return pixel.R(pos.X-halfX, pos.Y-halfY, pos.X+halfX, pos.Y+halfY)
+}
+
+// useItem uses the item player i picked, if it has any left.
+func useItem(i int, in playerInput) {
+	kind := players[i].selectedItem
+	if players[i].inventory[kind] <= 0 {
+		return
+	}
+
+	switch kind {
+	case itemBomb:
+		// The stick aims, left alone the goober blows up itself like it
+		// always did
+		aim := pixel.V(in.stickX, in.stickY).Scaled(.01)
+		if aim.Len() < .3 {
+			if players[i].exploding {
+				return
+			}
+			players[i].exploding = true
+			players[i].fuseLeft = explosionFuse
+			players[i].wearingHat = false
+			break
+		}
+		velocity := aim.Unit().Scaled(throwSpeed).Add(pixel.V(players[i].velocity.X, players[i].velocity.Y))
+		explosives = append(explosives, explosive{
+			kind:     itemBomb,
+			position: pixel.V(players[i].position.X, players[i].position.Y),
+			velocity: velocity,
+			lit:      true,
+			fuseLeft: explosionFuse,
+		})
+
+	case itemMine:
+		// Mines go on the ground
+		if !players[i].grounded {
+			return
+		}
+		_, blockSizeY := blockSize()
+		feet := hitbox(players[i].position).Min.Y
+		explosives = append(explosives, explosive{
+			kind:     itemMine,
+			position: pixel.V(players[i].position.X, feet+explosiveSize*blockSizeY/2),
+			armLeft:  mineArmTime,
+		})
+
+	case itemBoost:
+		players[i].boostLeft = boostDuration
+	}
+
+	players[i].inventory[kind] -= 1
+}
+
+// cycleItem picks the next kind of item player i has.
+func cycleItem(i int) {
+	for next := 1; next <= int(itemKinds); next++ {
+		kind := (players[i].selectedItem + item(next)) % itemKinds
+		if players[i].inventory[kind] > 0 {
+			players[i].selectedItem = kind
+			return
+		}
+	}
+}
+
+// giveItem gives player i a random item, once per tile.
+func giveItem(i, x, y int) {
+	if claimTile(i, x, y) {
+		players[i].inventory[rand.Intn(int(itemKinds))] += 1
+	}
+}
+
+// giveBomb gives player i a bomb, once per tile.
+func giveBomb(i, x, y int) {
+	if claimTile(i, x, y) {
+		players[i].inventory[itemBomb] += 1
+	}
+}
+
+// claimTile reports whether player i hasn't taken an item from the tile at
+// x, y yet, and remembers that it did.
+func claimTile(i, x, y int) bool {
+	for _, val := range players[i].claimedItems {
+		if val.X == x && val.Y == y {
+			return false
+		}
+	}
+	players[i].claimedItems = append(players[i].claimedItems, struct{ X, Y int }{x, y})
+	return true
+}
+
+// itemManager runs out boosts, moves thrown bombs and sets off the mines
+// goobers step on.
+func itemManager(deltaTime float64) {
+	tick := time.Duration(deltaTime * float64(time.Second))
+	for i := range players {
+		if players[i].boostLeft > 0 {
+			players[i].boostLeft -= tick
+		}
+	}
+
+	for i := range explosives {
+		e := &explosives[i]
+		switch e.kind {
+		case itemBomb:
+			//* Fly, bounce off walls and roll to a stop
+			e.velocity.Y -= gravity * deltaTime
+			moved, hit := moveBox(blockGrid, explosiveBox(e.position), e.velocity.X*deltaTime, e.velocity.Y*deltaTime)
+			e.position = e.position.Add(moved)
+			if hit.wall {
+				e.velocity.X *= -.5
+			}
+			if hit.ceiling || hit.landed {
+				e.velocity.Y = 0
+			}
+			if hit.landed {
+				e.velocity.X *= math.Max(0, 1-groundFriction*deltaTime)
+			}
+
+		case itemMine:
+			if e.armLeft > 0 {
+				e.armLeft -= tick
+				continue
+			}
+			if e.lit {
+				continue
+			}
+			box := explosiveBox(e.position)
+			for j := range players {
+				if !bumpable(j) {
+					continue
+				}
+				if overlap := box.Intersect(hitbox(players[j].position)); overlap.W() > 0 && overlap.H() > 0 {
+					e.lit = true
+					e.fuseLeft = 0
+					break
+				}
+			}
+		}
+	}
+}
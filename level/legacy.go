@@ -0,0 +1,68 @@
+package level
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LegacyExt is the extension of the old space separated level files.
+const LegacyExt = ".level"
+
+// ParseLegacy reads an old .level file: rows of space separated letters,
+// top row first, followed by a line with the time limit in seconds and the
+// spawn point. The last letter of every row was never used and is dropped.
+func ParseLegacy(data []byte) (*Level, error) {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 2 {
+		return nil, errors.New("level is too short")
+	}
+
+	l := &Level{
+		Version:    Version,
+		Background: NoBackground,
+	}
+
+	//* Tiles
+	for i, line := range lines[:len(lines)-1] {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("row %d is too short", i+1)
+		}
+		row := ""
+		for _, val := range fields[:len(fields)-1] {
+			if len(val) != 1 {
+				return nil, fmt.Errorf("row %d has tile %q, expected a single letter", i+1, val)
+			}
+			row += val
+		}
+		l.Tiles = append(l.Tiles, row)
+	}
+
+	//* Time limit and spawn
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("last line should be the time limit and spawn, got %q", lines[len(lines)-1])
+	}
+	var err error
+	if l.TimeLimit, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return nil, fmt.Errorf("time limit: %w", err)
+	}
+	var spawn Spawn
+	if spawn.X, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return nil, fmt.Errorf("spawn X: %w", err)
+	}
+	if spawn.Y, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return nil, fmt.Errorf("spawn Y: %w", err)
+	}
+	l.Spawns = []Spawn{spawn}
+
+	return l, nil
+}
@@ -0,0 +1,248 @@
+// Package level reads and writes Goobers level files.
+//
+// A level is a JSON file with some metadata and the tiles as one string per
+// row, top row first:
+//
+//	{
+//		"version": 1,
+//		"name": "Level 1",
+//		"timeLimit": 50,
+//		"spawns": [{"x": 2, "y": 4}],
+//		"tiles": [
+//			"NNNNNNNN...",
+//			"N//////L...",
+//			...
+//		]
+//	}
+//
+// Spawn points are in blocks, counted from the bottom left corner.
+package level
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Version is the newest format this package writes. Files with a newer
+// version are refused.
+const Version = 1
+
+// Ext is the extension of level files.
+const Ext = ".json"
+
+// Size of the classic levels, in blocks
+const (
+	DefaultWidth  = 39
+	DefaultHeight = 22
+)
+
+// DefaultTimeLimit is used when neither the level nor its pack has a time
+// limit, in seconds.
+const DefaultTimeLimit = 60
+
+// NoBackground leaves the plain sky behind the level.
+const NoBackground = -1
+
+// Tile letters used by the original levels
+const (
+	Empty   = '/'
+	Basic   = 'N'
+	Ability = 'A'
+	Lava    = 'L'
+	Finish  = 'F'
+)
+
+// Tile letters added later
+const (
+	Ice           = 'I'
+	Bounce        = 'B'
+	ConveyorLeft  = '<'
+	ConveyorRight = '>'
+	OneWay        = '-'
+	Crumble       = 'C'
+	Cracked       = 'X'
+)
+
+type Spawn struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Level struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Author  string `json:"author,omitempty"`
+
+	// In seconds, 0 uses the time limit of the pack
+	TimeLimit float64 `json:"timeLimit"`
+
+	Spawns []Spawn `json:"spawns"`
+
+	// Index into assets/backgrounds, or NoBackground
+	Background int `json:"background"`
+
+	// File in assets/music
+	Music string `json:"music,omitempty"`
+
+	// Questions asked before the level are picked from this category
+	TriviaCategory string `json:"triviaCategory,omitempty"`
+
+	// Whether goobers bump into each other, nil leaves it to the host
+	Bumping *bool `json:"bumping,omitempty"`
+
+	// One string per row, top row first
+	Tiles []string `json:"tiles"`
+}
+
+// New returns an empty level of the given size with a single spawn point.
+func New(width, height int) *Level {
+	l := &Level{
+		Version:    Version,
+		Name:       "Untitled",
+		TimeLimit:  DefaultTimeLimit,
+		Spawns:     []Spawn{{X: 2, Y: 4}},
+		Background: NoBackground,
+	}
+	row := make([]byte, width)
+	for i := range row {
+		row[i] = Empty
+	}
+	for i := 0; i < height; i++ {
+		l.Tiles = append(l.Tiles, string(row))
+	}
+	return l
+}
+
+// Clone returns a copy of l that can be changed without touching l.
+func (l *Level) Clone() *Level {
+	c := *l
+	c.Spawns = append([]Spawn(nil), l.Spawns...)
+	c.Tiles = append([]string(nil), l.Tiles...)
+	if l.Bumping != nil {
+		bumping := *l.Bumping
+		c.Bumping = &bumping
+	}
+	return &c
+}
+
+func (l *Level) Width() int {
+	if len(l.Tiles) == 0 {
+		return 0
+	}
+	return len(l.Tiles[0])
+}
+
+func (l *Level) Height() int {
+	return len(l.Tiles)
+}
+
+// Resize changes the size of l, keeping the bottom left corner where it is.
+// New tiles are Empty.
+func (l *Level) Resize(width, height int) {
+	if width < 1 || height < 1 {
+		return
+	}
+	resized := New(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			resized.SetTile(x, y, l.Tile(x, y))
+		}
+	}
+	l.Tiles = resized.Tiles
+}
+
+// Duration is the time limit of the level, or DefaultTimeLimit if it has
+// none. Use Pack.Duration for levels in a pack.
+func (l *Level) Duration() time.Duration {
+	if l.TimeLimit <= 0 {
+		return DefaultTimeLimit * time.Second
+	}
+	return time.Duration(l.TimeLimit * float64(time.Second))
+}
+
+// Tile returns the letter at x, y with y counted from the bottom like the
+// game does. Anything outside the level is Empty.
+func (l *Level) Tile(x, y int) byte {
+	row := l.Height() - 1 - y
+	if row < 0 || row >= l.Height() || x < 0 || x >= len(l.Tiles[row]) {
+		return Empty
+	}
+	return l.Tiles[row][x]
+}
+
+// SetTile changes the letter at x, y, counted from the bottom. Positions
+// outside the level are ignored.
+func (l *Level) SetTile(x, y int, tile byte) {
+	row := l.Height() - 1 - y
+	if row < 0 || row >= l.Height() || x < 0 || x >= len(l.Tiles[row]) {
+		return
+	}
+	b := []byte(l.Tiles[row])
+	b[x] = tile
+	l.Tiles[row] = string(b)
+}
+
+// Parse reads a level file. It only checks what is needed to use the level,
+// see the levelcheck package for everything else.
+func Parse(data []byte) (*Level, error) {
+	l := &Level{Background: NoBackground}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, err
+	}
+	if l.Version <= 0 {
+		return nil, errors.New("missing version")
+	}
+	if l.Version > Version {
+		return nil, fmt.Errorf("version %d is newer than %d", l.Version, Version)
+	}
+	if l.Height() == 0 || l.Width() == 0 {
+		return nil, errors.New("no tiles")
+	}
+	for i, row := range l.Tiles {
+		if len(row) != l.Width() {
+			return nil, fmt.Errorf("row %d has %d tiles, expected %d", i+1, len(row), l.Width())
+		}
+	}
+	if l.TimeLimit < 0 {
+		return nil, errors.New("time limit can't be negative")
+	}
+	if len(l.Spawns) == 0 {
+		return nil, errors.New("no spawn points")
+	}
+	return l, nil
+}
+
+// Load reads the level at path.
+func Load(path string) (*Level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Marshal returns l as it is stored on disk.
+func (l *Level) Marshal() ([]byte, error) {
+	l.Version = Version
+	data, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Save writes l to path.
+func (l *Level) Save(path string) error {
+	data, err := l.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,50 @@
+package level
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	l, err := Parse([]byte(`{"version": 1, "name": "Test", "timeLimit": 30, "spawns": [{"x": 1, "y": 1}], "tiles": ["NNN", "N/F"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Width() != 3 || l.Height() != 2 {
+		t.Errorf("size is %dx%d, want 3x2", l.Width(), l.Height())
+	}
+	// Rows are stored top row first, y counts from the bottom
+	if l.Tile(2, 0) != Finish || l.Tile(0, 1) != Basic {
+		t.Errorf("tiles are upside down")
+	}
+	if l.Background != NoBackground {
+		t.Errorf("background is %d, want none", l.Background)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"not JSON", `NNN`, "invalid character"},
+		{"no version", `{"spawns": [{"x": 1, "y": 1}], "tiles": ["N/N"]}`, "missing version"},
+		{"newer version", `{"version": 2, "spawns": [{"x": 1, "y": 1}], "tiles": ["N/N"]}`, "newer"},
+		{"no tiles", `{"version": 1, "spawns": [{"x": 1, "y": 1}], "tiles": []}`, "no tiles"},
+		{"empty rows", `{"version": 1, "spawns": [{"x": 1, "y": 1}], "tiles": [""]}`, "no tiles"},
+		{"uneven rows", `{"version": 1, "spawns": [{"x": 1, "y": 1}], "tiles": ["NNN", "N/"]}`, "row 2 has 2 tiles"},
+		{"negative time", `{"version": 1, "timeLimit": -1, "spawns": [{"x": 1, "y": 1}], "tiles": ["N/N"]}`, "time limit"},
+		{"no spawns", `{"version": 1, "timeLimit": 30, "tiles": ["N/N"]}`, "no spawn"},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.data))
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got %q, want it to mention %q", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package level
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ManifestName is the file in a pack folder describing the pack.
+const ManifestName = "pack.json"
+
+// Pack is a folder of levels, played in the order of Levels.
+type Pack struct {
+	Name        string `json:"name"`
+	Author      string `json:"author,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Used by levels without a time limit of their own, in seconds
+	TimeLimit float64 `json:"timeLimit,omitempty"`
+
+	// Level files in the pack folder
+	Levels []string `json:"levels"`
+
+	// Folder the pack was loaded from
+	Dir string `json:"-"`
+}
+
+// LoadPack reads the manifest in dir. Folders without one become a pack
+// with every level in them, sorted by number.
+func LoadPack(dir string) (*Pack, error) {
+	p := &Pack{}
+	data, err := os.ReadFile(path.Join(dir, ManifestName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		p.Name = path.Base(dir)
+		if p.Levels, err = findLevels(dir); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, p); err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Join(dir, ManifestName), err)
+		}
+	}
+	p.Dir = dir
+
+	if p.Name == "" {
+		p.Name = path.Base(dir)
+	}
+	if p.TimeLimit < 0 {
+		return nil, fmt.Errorf("%s: time limit can't be negative", dir)
+	}
+	if len(p.Levels) == 0 {
+		return nil, fmt.Errorf("%s: pack has no levels", dir)
+	}
+	return p, nil
+}
+
+// findLevels lists the level files in dir, 2.json before 10.json.
+func findLevels(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var levels []string
+	for _, file := range files {
+		if file.IsDir() || file.Name() == ManifestName || !strings.HasSuffix(file.Name(), Ext) {
+			continue
+		}
+		levels = append(levels, file.Name())
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		a, errA := strconv.Atoi(strings.TrimSuffix(levels[i], Ext))
+		b, errB := strconv.Atoi(strings.TrimSuffix(levels[j], Ext))
+		if errA != nil || errB != nil {
+			return levels[i] < levels[j]
+		}
+		return a < b
+	})
+	return levels, nil
+}
+
+// Load reads the level called name from the pack.
+func (p *Pack) Load(name string) (*Level, error) {
+	return Load(path.Join(p.Dir, name))
+}
+
+// Duration is how long l lasts in this pack.
+func (p *Pack) Duration(l *Level) time.Duration {
+	if l.TimeLimit <= 0 && p.TimeLimit > 0 {
+		return time.Duration(p.TimeLimit * float64(time.Second))
+	}
+	return l.Duration()
+}
+
+// Save writes the manifest into the pack folder.
+func (p *Pack) Save() error {
+	data, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(p.Dir, ManifestName), append(data, '\n'), 0644)
+}
@@ -0,0 +1,157 @@
+// Package levelcheck finds mistakes in levels before anyone has to play
+// them: wrong sizes, unknown tiles, spawns inside blocks and finishes that
+// can't be reached with the jumps the goobers have.
+package levelcheck
+
+import (
+	"fmt"
+	"math"
+
+	"main.go/level"
+)
+
+// Tile is what the checker needs to know about a tile letter.
+type Tile struct {
+	Solid  bool
+	Deadly bool
+
+	// Solid only from above, goobers pass through it from below and the sides
+	OneWay bool
+}
+
+// Rules describe the game the levels are checked against. Heights are in
+// blocks and y is counted from the bottom like in the level package.
+type Rules struct {
+	// Expected size, 0 accepts any size
+	Width, Height int
+
+	// Every letter a level may use, Empty included
+	Tiles map[byte]Tile
+
+	// Lowest row a goober can stand in, the floor is below it
+	Floor int
+
+	// How many blocks a single jump goes up and across
+	JumpHeight   int
+	JumpDistance int
+
+	// How many blocks a goober can run over deadly tiles before dying
+	DeadlyDistance int
+}
+
+// Problem is something wrong with a level. X and Y are -1 when it is not
+// about a single tile.
+type Problem struct {
+	X, Y    int
+	Message string
+
+	// The level can still be played, the checker may just be too strict
+	Warning bool
+}
+
+func (p Problem) String() string {
+	message := p.Message
+	if p.Warning {
+		message = "warning: " + message
+	}
+	if p.X < 0 || p.Y < 0 {
+		return message
+	}
+	return fmt.Sprintf("%d,%d: %s", p.X, p.Y, message)
+}
+
+// Playable reports whether problems are only warnings.
+func Playable(problems []Problem) bool {
+	for _, val := range problems {
+		if !val.Warning {
+			return false
+		}
+	}
+	return true
+}
+
+// Check returns every problem found in l. A level with nothing but warnings
+// is playable under rules.
+func Check(l *level.Level, rules Rules) []Problem {
+	var problems []Problem
+	add := func(x, y int, format string, args ...any) {
+		problems = append(problems, Problem{X: x, Y: y, Message: fmt.Sprintf(format, args...)})
+	}
+
+	//* Size
+	if rules.Width > 0 && l.Width() != rules.Width {
+		add(-1, -1, "level is %d blocks wide, expected %d", l.Width(), rules.Width)
+	}
+	if rules.Height > 0 && l.Height() != rules.Height {
+		add(-1, -1, "level is %d blocks high, expected %d", l.Height(), rules.Height)
+	}
+
+	//* Tiles
+	var finishes [][2]int
+	for y := 0; y < l.Height(); y++ {
+		for x := 0; x < l.Width(); x++ {
+			tile := l.Tile(x, y)
+			if _, ok := rules.Tiles[tile]; !ok {
+				add(x, y, "unknown tile %q", tile)
+			}
+			if tile == level.Finish {
+				finishes = append(finishes, [2]int{x, y})
+			}
+		}
+	}
+	if len(finishes) == 0 {
+		add(-1, -1, "level has no finish")
+	}
+
+	//* Spawns
+	w := newWalker(l, rules)
+	var starts [][2]int
+	for i, spawn := range l.Spawns {
+		x, y := int(math.Floor(spawn.X)), int(math.Floor(spawn.Y))
+		if x < 0 || x >= l.Width() || y < 0 || y >= l.Height() {
+			add(x, y, "spawn %d is outside the level", i+1)
+			continue
+		}
+		tile := rules.Tiles[l.Tile(x, y)]
+		if tile.Solid && !tile.OneWay {
+			add(x, y, "spawn %d is inside a solid block", i+1)
+			continue
+		}
+		if tile.Deadly {
+			add(x, y, "spawn %d is inside %q", i+1, l.Tile(x, y))
+			continue
+		}
+		landX, landY := w.fall(x, y)
+		if w.deadlyBelow(landX, landY) {
+			add(x, y, "spawn %d drops onto %q", i+1, l.Tile(landX, landY-1))
+		}
+		starts = append(starts, [2]int{landX, landY})
+	}
+
+	//* Finishes
+	if len(starts) == 0 {
+		return problems
+	}
+	reached := w.reachable(starts)
+	anyReached := false
+	for _, val := range finishes {
+		x, y := val[0], val[1]
+		// The finish counts when a goober stands on it, covered tiles are
+		// only there to make the finish look bigger
+		if !w.free(x, y+1) {
+			continue
+		}
+		// The walker only knows straight jumps, a finish it misses may
+		// still be reachable with the real jump arc
+		if reached[[2]int{x, y + 1}] {
+			anyReached = true
+		} else {
+			problems = append(problems, Problem{X: x, Y: y, Message: "finish can't be reached from any spawn", Warning: true})
+		}
+	}
+	if len(finishes) > 0 && !anyReached {
+		add(-1, -1, "no finish can be reached from the spawns")
+	}
+
+	return problems
+}
@@ -0,0 +1,201 @@
+package levelcheck
+
+import (
+	"strings"
+	"testing"
+
+	"main.go/level"
+)
+
+func testRules() Rules {
+	return Rules{
+		Tiles: map[byte]Tile{
+			level.Empty:   {},
+			level.Basic:   {Solid: true},
+			level.Finish:  {Solid: true},
+			level.Lava:    {Solid: true, Deadly: true},
+			level.OneWay:  {Solid: true, OneWay: true},
+			level.Ability: {Solid: true},
+		},
+		JumpHeight:     2,
+		JumpDistance:   3,
+		DeadlyDistance: 3,
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		tiles  []string
+		spawn  level.Spawn
+		change func(r *Rules)
+
+		// Every problem has to mention one of these, in order. Empty means
+		// the level has to be fine.
+		problems []string
+	}{
+		{
+			name: "jump onto the finish",
+			tiles: []string{
+				"NNNNNN",
+				"N////N",
+				"N///FN",
+				"N////N",
+			},
+			spawn: level.Spawn{X: 1.5, Y: .5},
+		},
+		{
+			name: "finish too high",
+			tiles: []string{
+				"NNNNNN",
+				"N////N",
+				"N///FN",
+				"N////N",
+				"N////N",
+			},
+			spawn:    level.Spawn{X: 1.5, Y: .5},
+			problems: []string{"4,2: warning: finish can't be reached", "no finish can be reached"},
+		},
+		{
+			name: "finish too far",
+			tiles: []string{
+				"NNNNNNNNN",
+				"N///////N",
+				"N///////N",
+				"NN/////FN",
+				"NNLLLLLNN",
+			},
+			spawn:    level.Spawn{X: 1.5, Y: 2.5},
+			change:   func(r *Rules) { r.JumpHeight = 1; r.DeadlyDistance = 0 },
+			problems: []string{"finish can't be reached", "no finish can be reached"},
+		},
+		{
+			name: "through a one way tile",
+			tiles: []string{
+				"NNNNNN",
+				"N////N",
+				"NN-FNN",
+				"N////N",
+				"N////N",
+			},
+			spawn:  level.Spawn{X: 1.5, Y: .5},
+			change: func(r *Rules) { r.JumpHeight = 3 },
+		},
+		{
+			name: "run over lava",
+			tiles: []string{
+				"NNNNNNNN",
+				"N//////N",
+				"NNLLLFNN",
+			},
+			spawn:  level.Spawn{X: 1.5, Y: 1.5},
+			change: func(r *Rules) { r.JumpHeight = 0 },
+		},
+		{
+			name: "too much lava",
+			tiles: []string{
+				"NNNNNNNN",
+				"N//////N",
+				"NNLLLLFN",
+			},
+			spawn:    level.Spawn{X: 1.5, Y: 1.5},
+			change:   func(r *Rules) { r.JumpHeight = 0 },
+			problems: []string{"finish can't be reached", "no finish can be reached"},
+		},
+		{
+			name: "one of two finishes out of reach",
+			tiles: []string{
+				"N////N",
+				"NF///N",
+				"N////N",
+				"N////N",
+				"N///FN",
+				"N////N",
+			},
+			spawn:    level.Spawn{X: 2.5, Y: .5},
+			problems: []string{"1,4: warning: finish can't be reached"},
+		},
+		{
+			name: "spawn inside a block",
+			tiles: []string{
+				"NNNNNN",
+				"N///FN",
+				"N////N",
+			},
+			spawn:    level.Spawn{X: .5, Y: .5},
+			problems: []string{"0,0: spawn 1 is inside a solid block"},
+		},
+		{
+			name: "spawn outside",
+			tiles: []string{
+				"NNNNNN",
+				"N///FN",
+				"N////N",
+			},
+			spawn:    level.Spawn{X: 9, Y: 1},
+			problems: []string{"spawn 1 is outside the level"},
+		},
+		{
+			name: "spawn over lava",
+			tiles: []string{
+				"NNNNNN",
+				"N////N",
+				"N///FN",
+				"N////N",
+				"NLLLLN",
+			},
+			spawn:    level.Spawn{X: 1.5, Y: 2.5},
+			problems: []string{"spawn 1 drops onto 'L'"},
+		},
+		{
+			name: "no finish",
+			tiles: []string{
+				"NNNNNN",
+				"N////N",
+				"N////N",
+			},
+			spawn:    level.Spawn{X: 1.5, Y: .5},
+			problems: []string{"level has no finish"},
+		},
+		{
+			name: "unknown tile and wrong size",
+			tiles: []string{
+				"NNNNNN",
+				"N////N",
+				"N//?FN",
+				"N////N",
+			},
+			spawn:    level.Spawn{X: 1.5, Y: .5},
+			change:   func(r *Rules) { r.Width = 39 },
+			problems: []string{"6 blocks wide, expected 39", "3,1: unknown tile '?'"},
+		},
+	}
+
+	for _, test := range tests {
+		rules := testRules()
+		if test.change != nil {
+			test.change(&rules)
+		}
+		l := &level.Level{Version: level.Version, Tiles: test.tiles, Spawns: []level.Spawn{test.spawn}}
+
+		problems := Check(l, rules)
+		if len(problems) != len(test.problems) {
+			t.Errorf("%s: got problems %v, want %d", test.name, problems, len(test.problems))
+			continue
+		}
+		for i, val := range problems {
+			if !strings.Contains(val.String(), test.problems[i]) {
+				t.Errorf("%s: got problem %q, want it to mention %q", test.name, val, test.problems[i])
+			}
+		}
+		playable := true
+		for _, val := range test.problems {
+			if !strings.Contains(val, "warning") {
+				playable = false
+			}
+		}
+		if Playable(problems) != playable {
+			t.Errorf("%s: playable is %v, want %v", test.name, !playable, playable)
+		}
+	}
+}
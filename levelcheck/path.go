@@ -0,0 +1,104 @@
+package levelcheck
+
+import (
+	"main.go/level"
+)
+
+// walker finds where a goober can go. Positions are the cell the goober is
+// in, standing positions have a solid tile or the floor right below them.
+type walker struct {
+	l     *level.Level
+	rules Rules
+}
+
+func newWalker(l *level.Level, rules Rules) walker {
+	return walker{l: l, rules: rules}
+}
+
+// free reports whether a goober can be in x, y. The sides and the top of
+// the level count as walls.
+func (w walker) free(x, y int) bool {
+	if x < 0 || x >= w.l.Width() || y < w.rules.Floor || y >= w.l.Height() {
+		return false
+	}
+	tile := w.rules.Tiles[w.l.Tile(x, y)]
+	return !tile.Solid || tile.OneWay
+}
+
+func (w walker) standing(x, y int) bool {
+	return y == w.rules.Floor || w.rules.Tiles[w.l.Tile(x, y-1)].Solid
+}
+
+// deadlyBelow reports whether standing in x, y hurts.
+func (w walker) deadlyBelow(x, y int) bool {
+	if y == w.rules.Floor {
+		return false
+	}
+	return w.rules.Tiles[w.l.Tile(x, y-1)].Deadly
+}
+
+// fall returns where a goober dropped in x, y lands.
+func (w walker) fall(x, y int) (int, int) {
+	for y > w.rules.Floor && !w.standing(x, y) {
+		y--
+	}
+	return x, y
+}
+
+// reachable walks and jumps from starts and returns every standing position
+// that can be reached. Goobers can cross up to DeadlyDistance deadly tiles
+// in a row, they are assumed to be fine again once back on safe ground.
+//
+// A jump goes straight up, then sideways at the top and then falls down,
+// which is a little stricter than the real arc.
+func (w walker) reachable(starts [][2]int) map[[2]int]bool {
+	// Best health left when standing in a position, in deadly tiles
+	best := map[[2]int]int{}
+	var queue [][2]int
+	visit := func(x, y, health int) {
+		x, y = w.fall(x, y)
+		if w.deadlyBelow(x, y) {
+			health--
+		} else {
+			health = w.rules.DeadlyDistance
+		}
+		pos := [2]int{x, y}
+		if old, ok := best[pos]; health < 0 || (ok && old >= health) {
+			return
+		}
+		best[pos] = health
+		queue = append(queue, pos)
+	}
+
+	for _, val := range starts {
+		visit(val[0], val[1], w.rules.DeadlyDistance+1)
+	}
+
+	for len(queue) > 0 {
+		x, y := queue[0][0], queue[0][1]
+		health := best[queue[0]]
+		queue = queue[1:]
+
+		//* Walk, falling off edges
+		for _, dx := range []int{-1, 1} {
+			if w.free(x+dx, y) {
+				visit(x+dx, y, health)
+			}
+		}
+
+		//* Jump
+		for up := 1; up <= w.rules.JumpHeight && w.free(x, y+up); up++ {
+			for _, dx := range []int{-1, 1} {
+				for across := 1; across <= w.rules.JumpDistance && w.free(x+dx*across, y+up); across++ {
+					visit(x+dx*across, y+up, health)
+				}
+			}
+		}
+	}
+
+	reached := map[[2]int]bool{}
+	for pos := range best {
+		reached[pos] = true
+	}
+	return reached
+}
@@ -0,0 +1,188 @@
+// Package levelgen makes random levels from a seed. Every level it returns
+// passes levelcheck, so the finish can always be reached from the spawn.
+package levelgen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"main.go/level"
+	"main.go/levelcheck"
+)
+
+// Difficulty changes what the generator puts in a level.
+type Difficulty struct {
+	// Chance for every block of the floor and of the extra platforms to be
+	// lava, from 0 to 1
+	LavaDensity float64
+
+	// Widest gap between two platforms on the way to the finish, in blocks.
+	// It is never more than a jump can cross.
+	MaxGap int
+
+	// Ability blocks, which give out items
+	Bombs int
+}
+
+// Difficulties players can pick by name
+var Difficulties = map[string]Difficulty{
+	"easy":   {LavaDensity: 0.05, MaxGap: 3, Bombs: 6},
+	"normal": {LavaDensity: 0.15, MaxGap: 5, Bombs: 4},
+	"hard":   {LavaDensity: 0.3, MaxGap: 8, Bombs: 2},
+}
+
+// How many levels are thrown away before giving up on the difficulty and
+// making a safe one
+const maxAttempts = 100
+
+// Generate makes a level from seed. The same seed, difficulty and rules
+// always make the same level.
+func Generate(seed int64, d Difficulty, rules levelcheck.Rules) (*level.Level, error) {
+	r := rand.New(rand.NewSource(seed))
+
+	var l *level.Level
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		l = build(r, d, rules, true)
+		if len(levelcheck.Check(l, rules)) == 0 {
+			l.Name = fmt.Sprint("Generated ", seed)
+			return l, nil
+		}
+	}
+
+	// A staircase without lava or extra platforms always works
+	l = build(r, Difficulty{MaxGap: 1}, rules, false)
+	if problems := levelcheck.Check(l, rules); len(problems) > 0 {
+		return nil, fmt.Errorf("can't generate a level for these rules: %s", problems[0])
+	}
+	l.Name = fmt.Sprint("Generated ", seed)
+	return l, nil
+}
+
+type platform struct {
+	x, y, length int
+}
+
+// build makes one level, which may still be impossible when the extra
+// platforms or the lava get in the way.
+func build(r *rand.Rand, d Difficulty, rules levelcheck.Rules, extras bool) *level.Level {
+	width, height := rules.Width, rules.Height
+	if width <= 0 {
+		width = level.DefaultWidth
+	}
+	if height <= 0 {
+		height = level.DefaultHeight
+	}
+	l := level.New(width, height)
+
+	//* Walls and ceiling
+	for y := 0; y < height; y++ {
+		l.SetTile(0, y, level.Basic)
+		l.SetTile(width-1, y, level.Basic)
+	}
+	for x := 0; x < width; x++ {
+		l.SetTile(x, height-1, level.Basic)
+	}
+
+	floor := rules.Floor
+	l.Spawns = []level.Spawn{{X: 2.5, Y: float64(floor) + .5}}
+
+	//* Lava on the floor, away from the spawn
+	for x := 5; x < width-1; x++ {
+		if r.Float64() < d.LavaDensity {
+			l.SetTile(x, floor, level.Lava)
+		}
+	}
+
+	//* Platforms up to the finish
+	// Every platform is higher than the last, so nothing is ever in the way
+	// of the jump to the next one
+	maxRise := rules.JumpHeight
+	if maxRise > 4 {
+		maxRise = 4
+	}
+	maxGap := d.MaxGap
+	if maxGap > rules.JumpDistance-1 {
+		maxGap = rules.JumpDistance - 1
+	}
+	if maxGap < 1 {
+		maxGap = 1
+	}
+	x, y, dir := 2, floor, 1
+	var path []platform
+	for tries := 0; tries < 100 && maxRise > 0; tries++ {
+		rise := 1 + r.Intn(maxRise)
+		if y+rise > height-3 {
+			break
+		}
+		gap := 1 + r.Intn(maxGap)
+		length := 3 + r.Intn(4)
+
+		start := x + dir*(gap+1)
+		end := start + dir*(length-1)
+		left, right := start, end
+		if dir < 0 {
+			left, right = end, start
+		}
+		if left < 1 || right > width-2 {
+			dir = -dir
+			continue
+		}
+
+		y += rise
+		p := platform{x: left, y: y - 1, length: length}
+		for i := 0; i < p.length; i++ {
+			l.SetTile(p.x+i, p.y, level.Basic)
+		}
+		path = append(path, p)
+		x = end
+	}
+
+	//* Extra platforms, some of them lava
+	for i := 3 + r.Intn(4); extras && i > 0 && height-floor > 5; i-- {
+		p := platform{x: 1 + r.Intn(width-2), y: floor + 2 + r.Intn(height-floor-5), length: 2 + r.Intn(4)}
+		if !fits(l, p) {
+			continue
+		}
+		for x := p.x; x < p.x+p.length; x++ {
+			tile := byte(level.Basic)
+			if r.Float64() < d.LavaDensity {
+				tile = level.Lava
+			}
+			l.SetTile(x, p.y, tile)
+		}
+	}
+
+	//* Finish on the last platform
+	if len(path) == 0 {
+		// Not even one jump fits, finish on the floor across the level
+		l.SetTile(width-3, floor, level.Finish)
+		l.SetTile(width-2, floor, level.Finish)
+		return l
+	}
+	last := path[len(path)-1]
+	for i := 0; i < last.length; i++ {
+		l.SetTile(last.x+i, last.y, level.Finish)
+	}
+	path = path[:len(path)-1]
+
+	//* Ability blocks on the way
+	for i := 0; i < d.Bombs && len(path) > 0; i++ {
+		p := path[r.Intn(len(path))]
+		l.SetTile(p.x+r.Intn(p.length), p.y, level.Ability)
+	}
+
+	return l
+}
+
+// fits reports whether p and the blocks around it are empty, so extra
+// platforms never touch the path.
+func fits(l *level.Level, p platform) bool {
+	for x := p.x - 1; x <= p.x+p.length; x++ {
+		for y := p.y - 1; y <= p.y+1; y++ {
+			if x < 1 || x >= l.Width()-1 || l.Tile(x, y) != level.Empty {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package levelgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"main.go/level"
+	"main.go/levelcheck"
+)
+
+// How many seeds every difficulty is tried with
+const testSeeds = 50
+
+func testTiles() map[byte]levelcheck.Tile {
+	return map[byte]levelcheck.Tile{
+		level.Empty:   {},
+		level.Basic:   {Solid: true},
+		level.Ability: {Solid: true},
+		level.Finish:  {Solid: true},
+		level.Lava:    {Solid: true, Deadly: true},
+	}
+}
+
+func testRules() map[string]levelcheck.Rules {
+	return map[string]levelcheck.Rules{
+		// What lint-levels measures in the game
+		"game": {Width: level.DefaultWidth, Height: level.DefaultHeight, Tiles: testTiles(), Floor: 2, JumpHeight: 6, JumpDistance: 27, DeadlyDistance: 10},
+		"big":  {Width: 80, Height: 40, Tiles: testTiles(), Floor: 2, JumpHeight: 6, JumpDistance: 27, DeadlyDistance: 10},
+	}
+}
+
+func TestGenerateSaves(t *testing.T) {
+	for rulesName, rules := range testRules() {
+		for name, d := range Difficulties {
+			for seed := int64(0); seed < testSeeds; seed++ {
+				l, err := Generate(seed, d, rules)
+				if err != nil {
+					t.Fatalf("%s rules, %s, seed %d: %v", rulesName, name, seed, err)
+				}
+
+				// Generated levels go through the same files as the others
+				data, err := l.Marshal()
+				if err != nil {
+					t.Fatalf("%s rules, %s, seed %d: %v", rulesName, name, seed, err)
+				}
+				loaded, err := level.Parse(data)
+				if err != nil {
+					t.Fatalf("%s rules, %s, seed %d: saved level doesn't load: %v", rulesName, name, seed, err)
+				}
+				if loaded.Width() != rules.Width || loaded.Height() != rules.Height {
+					t.Fatalf("%s rules, %s, seed %d: level is %dx%d", rulesName, name, seed, loaded.Width(), loaded.Height())
+				}
+				if !reflect.DeepEqual(loaded.Tiles, l.Tiles) {
+					t.Fatalf("%s rules, %s, seed %d: tiles changed while saving", rulesName, name, seed)
+				}
+			}
+		}
+	}
+}
+
+func TestHardHasMoreLava(t *testing.T) {
+	rules := testRules()["game"]
+	lava := func(d Difficulty) int {
+		count := 0
+		for seed := int64(0); seed < testSeeds; seed++ {
+			l, err := Generate(seed, d, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range l.Tiles {
+				count += strings.Count(row, string(level.Lava))
+			}
+		}
+		return count
+	}
+
+	if easy, hard := lava(Difficulties["easy"]), lava(Difficulties["hard"]); hard <= easy {
+		t.Errorf("hard levels have %d lava tiles, easy ones %d", hard, easy)
+	}
+}
+
+func TestGenerateFallback(t *testing.T) {
+	// Lava everywhere but no lava allowed, only the safe staircase passes
+	rules := testRules()["game"]
+	delete(rules.Tiles, level.Lava)
+	d := Difficulty{LavaDensity: 1, MaxGap: 8}
+
+	// Every seed throws away maxAttempts levels first, a few are enough
+	for seed := int64(0); seed < 5; seed++ {
+		l, err := Generate(seed, d, rules)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if problems := levelcheck.Check(l, rules); len(problems) > 0 {
+			t.Fatalf("seed %d: %v", seed, problems)
+		}
+	}
+
+	// Not even the staircase works without a finish
+	delete(rules.Tiles, level.Finish)
+	if _, err := Generate(0, d, rules); err == nil {
+		t.Errorf("expected an error for rules without a finish")
+	}
+}
+
+func TestGenerateSameSeed(t *testing.T) {
+	rules := testRules()["game"]
+	for name, d := range Difficulties {
+		different := false
+		for seed := int64(0); seed < testSeeds; seed++ {
+			a, err := Generate(seed, d, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Generate(seed, d, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a, b) {
+				t.Fatalf("%s, seed %d: made two different levels", name, seed)
+			}
+
+			next, err := Generate(seed+1, d, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a.Tiles, next.Tiles) {
+				different = true
+			}
+		}
+		if !different {
+			t.Errorf("%s: every seed made the same level", name)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+var hostPIN = flag.String("pin", "", "PIN a controller can enter to become the host")
+
+// Players get a small ID the phones can use to point at each other, the
+// session token stays secret
+var nextPlayerID = 1
+
+// Set whenever something shown in the lobby changes so the roster is sent
+// again on the next tick
+var rosterChanged = false
+
+// Picked by the host, levels can turn it on or off for themselves
+var bumping = false
+
+func findPlayerByID(ID int) int {
+	for i := range players {
+		if players[i].id == ID {
+			return i
+		}
+	}
+	return -1
+}
+
+// ensureHost hands the host role to the first connected player when nobody
+// has it. With a PIN set only players that know it can be host.
+func ensureHost() {
+	if *hostPIN != "" {
+		return
+	}
+	for i := range players {
+		if players[i].host && players[i].connected {
+			return
+		}
+	}
+	for i := range players {
+		players[i].host = false
+	}
+	for i := range players {
+		if players[i].connected {
+			players[i].host = true
+			rosterChanged = true
+			fmt.Println("New host: ", players[i].playerName)
+			return
+		}
+	}
+}
+
+func allPlayersReady() bool {
+	if connectedPlayers(players) == 0 {
+		return false
+	}
+	for i := range players {
+		if players[i].connected && !players[i].ready {
+			return false
+		}
+	}
+	return true
+}
+
+// lobbyCommand handles the lobby messages of the player with token. It must
+// run on the game goroutine.
+func lobbyCommand(token string, msg clientMessage) {
+	playerID := findPlayerByToken(token)
+	if playerID == -1 {
+		return
+	}
+	c := players[playerID].conn
+
+	switch msg.Type {
+	case msgReady:
+		if !inLobby() {
+			return
+		}
+		players[playerID].ready = msg.Ready
+		rosterChanged = true
+
+	case msgHost:
+		if *hostPIN == "" || msg.PIN != *hostPIN {
+			c.send(newErrorMessage(errNotHost, errors.New("wrong PIN")))
+			return
+		}
+		for i := range players {
+			players[i].host = i == playerID
+		}
+		rosterChanged = true
+
+	case msgStart, msgKick, msgPack, msgPlaylist, msgBumping:
+		if !players[playerID].host {
+			c.send(newErrorMessage(errNotHost, errors.New("only the host can do that")))
+			return
+		}
+		if !inLobby() {
+			return
+		}
+		hostCommand(playerID, msg)
+
+	case msgPause:
+		if !players[playerID].host {
+			c.send(newErrorMessage(errNotHost, errors.New("only the host can pause")))
+			return
+		}
+		setPaused(msg.Paused)
+	}
+}
+
+func hostCommand(playerID int, msg clientMessage) {
+	c := players[playerID].conn
+
+	switch msg.Type {
+	case msgStart:
+		startGame()
+
+	case msgKick:
+		kickedID := findPlayerByID(msg.Player)
+		if kickedID == -1 || kickedID == playerID {
+			return
+		}
+		kicked := players[kickedID]
+		players = append(players[:kickedID], players[kickedID+1:]...)
+		if kicked.connected {
+			kicked.conn.send(newErrorMessage(errKicked, errors.New("the host kicked you")))
+			kicked.conn.closeAfterSending()
+		}
+		rosterChanged = true
+		fmt.Println("Player kicked: ", kicked.playerName)
+
+	case msgPack:
+		if err := selectPack(msg.Pack); err != nil {
+			c.send(newErrorMessage(errBadMessage, err))
+		}
+
+	case msgPlaylist:
+		if err := selectPlaylist(msg.Mode, msg.Count, msg.Difficulty); err != nil {
+			c.send(newErrorMessage(errBadMessage, err))
+		}
+
+	case msgBumping:
+		bumping = msg.Bumping
+		rosterChanged = true
+	}
+}
+
+// sendRoster tells every phone who is in the game when the lobby changed.
+func sendRoster() {
+	ensureHost()
+
+	if !rosterChanged {
+		return
+	}
+	rosterChanged = false
+	msg := newRosterMessage(players, inLobby())
+	msg.Pack = packID(currentPack)
+	msg.Packs = newPackInfos()
+	msg.Playlist = playlistMode
+	msg.Count = playlistCount
+	msg.Difficulty = playlistDifficulty
+	msg.Bumping = bumping
+	broadcastState(msgRoster, msg)
+}
@@ -1,1563 +1,1728 @@
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"image"
-	"math"
-	"math/rand"
-	"net"
-	"net/http"
-	"os"
-	"path"
-	"strconv"
-	"strings"
-	"time"
-
-	_ "image/png"
-
-	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
-	"github.com/faiface/pixel/text"
-	"github.com/gorilla/websocket"
-	"golang.org/x/image/colornames"
-	"golang.org/x/image/font/basicfont"
-)
-
-var wd string
-var maxHats int
-var maxChars int
-var numOfLevels int
-
-const windowX = 1280
-const windowY = 720
-const bottomFloor = 100
-const gravity = 1000
-const globalTerminalVelocityX = 1000
-const globalTerminalVelocityY = 1000
-const globalJumpPower = 300.
-const gloablSpeed = 35.
-const constantXLoss = 5.
-const lavaDamage = 100
-const explosionFuse = time.Second * 1
-const explosionDamage = 0
-const explosionPower = 10000
-const explosionSpread = 1.
-const explosionDecay = .01
-const minBombsLeft = 3
-const correctAnswerPoints = 5000.
-const maxLevelPoints = 10000.
-const nonCompletionPenalty = 1000
-const podiumDisplayTime = time.Second * 5
-
-const blocksPerRow = 39.
-const blocksPerCollumn = 22.
-
-var gameStarted = false
-var gameLogs = ""
-
-type player struct {
-	playerName       string
-	hatID            int
-	characterID      int
-	animation        string
-	wearingHat       bool
-	IP               string
-	ws               *websocket.Conn
-	winner           bool
-	score            float64
-	position         struct{ X, Y float64 }
-	acceleration     struct{ X, Y float64 }
-	terminalVelocity struct{ X, Y float64 }
-	grounded         bool
-	jumpPower        float64
-	speed            float64
-	bombsLeft        int
-	health           float64
-	finishDuration   time.Duration
-	exploding        bool
-	explosionFuse    time.Time
-	claimedBombs     []struct{ X, Y int }
-}
-
-type goober struct {
-	idle          pixel.Sprite
-	walking_right pixel.Sprite
-	walking_left  pixel.Sprite
-	falling       pixel.Sprite
-	exploding     pixel.Sprite
-}
-
-type block struct {
-	blockType string
-}
-
-type particle struct {
-	created  time.Time
-	lifespan time.Duration
-	position pixel.Vec
-	sprite   pixel.Sprite
-}
-
-type question struct {
-	Question string `json:"Question"`
-	Corect   string `json:"Correct"`
-	Alt1     string `json:"Alt1"`
-	Alt2     string `json:"Alt2"`
-}
-
-var players []player
-var blockGrid [][]block
-var particles []particle
-var questions []question
-
-func _init() {
-	//* Get wd
-	var err error
-	wd, err = os.Getwd()
-	if err != nil {
-		panic(err)
-	}
-
-	//* Get maxElems
-	characters, err := os.ReadDir(path.Join(wd, "\\assets\\characters"))
-	if err != nil {
-		panic(err)
-	}
-	hats, err := os.ReadDir(path.Join(wd, "\\assets\\hats"))
-	if err != nil {
-		panic(err)
-	}
-	maxChars = len(characters)
-	maxHats = len(hats)
-
-	//* Build blockgrid
-	for i := 0; i < blocksPerRow; i++ {
-		var row []block
-		for j := 0; j < blocksPerCollumn; j++ {
-			row = append(row, block{blockType: ""})
-		}
-		blockGrid = append(blockGrid, row)
-	}
-
-	//* Get questions
-	rawQuestions, err := os.ReadFile(path.Join(wd, "questions.json"))
-	if err != nil {
-		panic(err)
-	}
-	err = json.Unmarshal(rawQuestions, &questions)
-	if err != nil {
-		panic(err)
-	}
-
-	//* Get num of levels
-	levels, err := os.ReadDir(path.Join(wd, "/levels/normal/"))
-	if err != nil {
-		panic(err)
-	}
-	numOfLevels = len(levels)
-}
-
-func readHTML(name string) string {
-	f, err := os.ReadFile(path.Join(wd, "\\static\\", name+".html"))
-	if err != nil {
-		fmt.Println("Failed to read file ", name)
-		panic(err)
-	}
-	return string(f)
-}
-
-func main() {
-	_init()
-
-	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("assets"))))
-	http.Handle("/scripts/", http.StripPrefix("/scripts/", http.FileServer(http.Dir("scripts"))))
-	http.Handle("/styles/", http.StripPrefix("/styles/", http.FileServer(http.Dir("styles"))))
-
-	http.HandleFunc("/", handleControls)
-	http.HandleFunc("/ws", handleWebSocket)
-
-	go func() {
-		err := http.ListenAndServe(":80", nil)
-		if err != nil {
-			fmt.Println("All the controllers have been disconected!")
-		}
-	}()
-
-	fmt.Println("Started controllers server!")
-
-	pixelgl.Run(run)
-}
-
-func handleControls(w http.ResponseWriter, r *http.Request) {
-	html := readHTML("root")
-
-	html = strings.ReplaceAll(html, "'{{ MaxHats }}'", fmt.Sprint(maxHats))
-	html = strings.ReplaceAll(html, "'{{ MaxChars }}'", fmt.Sprint(maxChars))
-
-	fmt.Fprint(w, html)
-}
-
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow any origin
-	},
-}
-
-func findPlayerByIP(ip string) int {
-	var ID = -1
-
-	for i := 0; i < len(players); i++ {
-		if players[i].IP == ip {
-			ID = i
-			break
-		}
-	}
-
-	return ID
-}
-
-func dist(x float64, y float64, a float64, b float64) float64 {
-	return math.Sqrt((x-a)*(x-a) + (y-b)*(y-b))
-}
-
-func handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	for {
-		// Wait for connections
-		conn, err := upgrader.Upgrade(w, r, nil)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-
-		fmt.Println("Client connected")
-
-		go func() {
-			for {
-				_, msg, err := conn.ReadMessage()
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-
-				go websocketLogic(msg, conn)
-			}
-		}()
-	}
-
-}
-
-func websocketLogic(msg []byte, conn *websocket.Conn) {
-	// Add new players
-	if string(msg[:3]) == "NEW" {
-		thisHatID, err := strconv.Atoi(strings.Split(string(msg), " ")[1])
-		if err != nil {
-			fmt.Println("Failed to register player.")
-			return
-		}
-		thisCharacterID, err := strconv.Atoi(strings.Split(string(msg), " ")[2])
-		if err != nil {
-			fmt.Println("Failed to register player.")
-			return
-		}
-		players = append(players, player{
-			hatID:        thisHatID,
-			characterID:  thisCharacterID,
-			wearingHat:   true,
-			playerName:   strings.Split(string(msg), " ")[3],
-			animation:    "idle",
-			IP:           conn.RemoteAddr().String(),
-			ws:           conn,
-			winner:       false,
-			score:        0,
-			position:     struct{ X, Y float64 }{0.0, windowY},
-			acceleration: struct{ X, Y float64 }{0.0, -100.0},
-			terminalVelocity: struct {
-				X float64
-				Y float64
-			}{globalTerminalVelocityX, globalTerminalVelocityY},
-			grounded:     true,
-			jumpPower:    globalJumpPower,
-			speed:        gloablSpeed,
-			bombsLeft:    minBombsLeft,
-			health:       100,
-			claimedBombs: []struct{ X, Y int }{},
-		})
-		fmt.Println("New player: ", players[len(players)-1])
-		return
-	}
-
-	// Check jumps
-	playerID := findPlayerByIP(conn.RemoteAddr().String())
-	if playerID == -1 {
-		return
-	}
-	if string(msg) == "BTN GREEN" && gameStarted && players[playerID].grounded {
-		players[playerID].acceleration.Y += players[playerID].jumpPower
-		players[playerID].grounded = false
-	}
-
-	// Check movements
-	if string(msg[:3]) == "BAL" && gameStarted {
-		ballX, err := strconv.ParseFloat(strings.Split(string(msg), " ")[1], 64)
-		if err != nil {
-			fmt.Println("Player submited invalid value for BAL")
-			return
-		}
-		players[playerID].acceleration.X += players[playerID].speed * deltaTime * ballX
-
-	}
-
-	if string(msg) == "BTN RED" && gameStarted && players[playerID].bombsLeft > 0 && !players[playerID].exploding {
-		players[playerID].exploding = true
-		players[playerID].explosionFuse = time.Now()
-		players[playerID].wearingHat = false
-
-		// Remove bombs from inventory
-		players[playerID].bombsLeft -= 1
-	}
-
-	if string(msg[:3]) == "RSP" && gameStarted {
-		if string(msg[4:]) == triviaAnswer {
-			players[playerID].bombsLeft += 1
-			players[playerID].score += correctAnswerPoints
-		}
-	}
-}
-
-func notifyController(t time.Duration) {
-	for {
-		if !gameStarted {
-			continue
-		}
-		for i := range players {
-			message := fmt.Sprintf("BOM\\\\%s\\\\%d", players[i].playerName, players[i].bombsLeft)
-			players[i].ws.WriteMessage(websocket.TextMessage, []byte(message))
-
-			message = fmt.Sprintf("HEL\\\\%s\\\\%f", players[i].playerName, players[i].health)
-			players[i].ws.WriteMessage(websocket.TextMessage, []byte(message))
-
-			time.Sleep(t)
-		}
-	}
-}
-
-func loadPicture(path string) (pixel.Picture, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-	return pixel.PictureDataFromImage(img), nil
-}
-
-func getPrivateIP() (string, error) {
-	toReturn := ""
-	interfaces, err := net.Interfaces()
-	if err != nil {
-		return "", err
-	}
-
-	for _, iface := range interfaces {
-		// Skip loopback and non-up interfaces
-		if (iface.Flags&net.FlagLoopback == 0) && (iface.Flags&net.FlagUp != 0) {
-			addrs, err := iface.Addrs()
-			if err != nil {
-				return "", err
-			}
-
-			for _, addr := range addrs {
-				ip, _, err := net.ParseCIDR(addr.String())
-				if err != nil {
-					return "", err
-				}
-
-				// Check if the IP address is a private IPv4 address
-				if ip.To4() != nil && (ip.IsLoopback() || ip.IsPrivate()) {
-					toReturn = ip.String()
-				}
-			}
-		}
-	}
-	if toReturn == "" {
-		return "", fmt.Errorf("no private ip address found")
-	} else {
-		return toReturn, nil
-	}
-}
-
-func gravityHandler(deltaTime float64) {
-	defer func() {
-		if r := recover(); r != nil {
-			gameLogs += fmt.Sprint("Line 369: ", r, "\n")
-		}
-	}()
-
-	for i := range players {
-		var feetTouchingBlock bool
-		blockSizeX := win.Bounds().W() / blocksPerRow
-		blockSizeY := win.Bounds().H()/blocksPerCollumn + 1
-
-		touchingBlock := blockGrid[int(math.Floor(players[i].position.X/blockSizeX))][int(math.Floor((players[i].position.Y-blockSizeY/2)/blockSizeY))]
-		if touchingBlock.blockType != "" {
-			feetTouchingBlock = true
-		}
-
-		if math.Abs(players[i].position.Y-bottomFloor) <= 10 || feetTouchingBlock {
-			players[i].acceleration.Y += math.Abs(players[i].acceleration.Y)
-			players[i].grounded = true
-
-		OuterSwitch:
-			switch touchingBlock.blockType {
-			case "lava":
-				players[i].health -= lavaDamage * deltaTime
-
-			case "ability":
-				// Find if the bomb has been claimed
-				for j := range players[i].claimedBombs {
-					if players[i].claimedBombs[j] == struct {
-						X int
-						Y int
-					}{int(math.Floor(players[i].position.X / blockSizeX)), int(math.Floor((players[i].position.Y - blockSizeY/2) / blockSizeY))} {
-						break OuterSwitch
-					}
-				}
-
-				// Give out bombs
-				players[i].bombsLeft += 1
-				players[i].claimedBombs = append(players[i].claimedBombs, struct {
-					X int
-					Y int
-				}{int(math.Floor(players[i].position.X / blockSizeX)), int(math.Floor((players[i].position.Y - blockSizeY/2) / blockSizeY))})
-
-			case "finish":
-				players[i].winner = true
-				players[i].health = 0
-				players[i].finishDuration = time.Since(currentLevelStartTime)
-			default:
-				break
-			}
-			continue
-		}
-		if players[i].position.Y < bottomFloor-10 {
-			players[i].position.Y = bottomFloor + 20
-		}
-
-		players[i].acceleration.Y -= deltaTime * gravity
-	}
-}
-
-func movementHandler(deltaTime float64) {
-	for i, val := range players {
-		//gridPositionY := int(math.Round((val.position.Y) / 50))
-		//gridPositionX := int(math.Round((val.position.X - 25) / 50))
-
-		// Check if everything is ok
-		if val.acceleration.Y > val.terminalVelocity.Y {
-			players[i].acceleration.Y = val.terminalVelocity.Y
-		}
-		if val.acceleration.X > val.terminalVelocity.X {
-			players[i].acceleration.X = val.terminalVelocity.X
-		}
-		if val.acceleration.X < -val.terminalVelocity.X {
-			players[i].acceleration.X = -val.terminalVelocity.X
-		}
-		if val.acceleration.Y < -val.terminalVelocity.Y {
-			players[i].acceleration.Y = -val.terminalVelocity.Y
-		}
-
-		// Calculate movements
-		changedX := float64(deltaTime) * players[i].acceleration.X
-		changedY := float64(deltaTime) * players[i].acceleration.Y
-
-		// Apply movements
-		players[i].acceleration.X -= changedX
-		players[i].acceleration.Y -= changedY
-		// Apply some resistance
-		if changedX == 0 {
-			continue
-		}
-		players[i].acceleration.X -= (changedX / math.Abs(changedX)) * constantXLoss
-
-		// Make sure we don't crash
-		defer func() {
-			if r := recover(); r != nil {
-				gameLogs += fmt.Sprint("Line 489: ", r, "\n")
-				players[i].position.X += changedX
-				players[i].position.Y += changedY
-			}
-		}()
-
-		// Stop at ceilings
-		blockSizeX := win.Bounds().W() / blocksPerRow
-		blockSizeY := win.Bounds().H()/blocksPerCollumn + 1
-		touchingBlock := blockGrid[int(math.Floor(players[i].position.X/blockSizeX))][int(math.Floor((players[i].position.Y-blockSizeY/2)/blockSizeY))+1]
-		if touchingBlock.blockType != "" {
-			if changedY > 0 {
-				changedY = 0
-			}
-		}
-
-		// Stop at righ wall
-		touchingBlock = blockGrid[int(math.Floor((players[i].position.X-blockSizeX/2)/blockSizeX))+1][int(math.Floor((players[i].position.Y)/blockSizeY))]
-		if touchingBlock.blockType != "" && changedX > 0 {
-			changedX = 0
-		}
-
-		// Stop at left wall
-		touchingBlock = blockGrid[int(math.Floor((players[i].position.X+blockSizeX/2)/blockSizeX))-1][int(math.Floor((players[i].position.Y)/blockSizeY))]
-		if touchingBlock.blockType != "" && changedX < 0 {
-			changedX = 0
-		}
-
-		players[i].position.X += changedX
-		players[i].position.Y += changedY
-	}
-}
-
-func basicAnimator() {
-	for {
-		if !gameStarted {
-			continue
-		}
-		for i := range players {
-			if players[i].exploding {
-				players[i].animation = "exploding"
-			} else if players[i].acceleration.Y < 0 {
-				players[i].animation = "falling"
-			} else if math.Abs(players[i].acceleration.X) > 10 {
-				if players[i].acceleration.X > 0 {
-					players[i].animation = "walking-right"
-				} else {
-					players[i].animation = "walking-left"
-				}
-			} else {
-				players[i].animation = "idle"
-			}
-		}
-	}
-}
-
-func explosionManager() {
-	thisIMG, err := loadPicture(path.Join(wd, "/assets/particles/explosion.png"))
-	if err != nil {
-		panic(err)
-	}
-	explosion := pixel.NewSprite(thisIMG, thisIMG.Bounds())
-
-	for {
-		time.Sleep(time.Millisecond * 100)
-		if !gameStarted {
-			continue
-		}
-		for i, val := range players {
-			if !val.exploding {
-				continue
-			}
-			if time.Since(val.explosionFuse) < explosionFuse {
-				continue
-			}
-
-			// Make player back
-			players[i].exploding = false
-			players[i].wearingHat = true
-
-			// Schedule some particles
-			particles = append(particles, particle{
-				created:  time.Now(),
-				lifespan: time.Second * 1,
-				position: val.position,
-				sprite:   *explosion,
-			})
-
-			// Affect players
-			go func(val player) {
-				for j, vall := range players {
-					if vall.IP == val.IP {
-						continue
-					}
-					d := dist(vall.position.X, vall.position.Y, val.position.X, val.position.Y)
-					dx := math.Abs(vall.position.X - val.position.X)
-					dy := math.Abs(vall.position.Y - val.position.Y)
-					pow := explosionPower * math.Exp(-explosionDecay*d) * explosionSpread
-					ratio := d / (d + pow)
-					powy := dy/ratio + (vall.position.Y-val.position.Y)/dy*val.position.Y
-					powx := dx/ratio + (vall.position.X-val.position.X)/dx*val.position.X
-
-					players[j].acceleration.X += powx
-					players[j].acceleration.Y += powy
-
-					players[j].health -= pow * explosionDamage
-
-				}
-			}(val)
-		}
-	}
-}
-
-func askPlayers() int {
-	q := questions[rand.Intn(len(questions))]
-	var r1, r2, r3 string
-	var toReturn int
-	switch rand.Intn(6) {
-	case 0:
-		r1 = q.Alt1
-		r2 = q.Alt2
-		r3 = q.Corect
-		toReturn = 3
-	case 1:
-		r1 = q.Alt1
-		r2 = q.Corect
-		r3 = q.Alt2
-		toReturn = 2
-	case 2:
-		r1 = q.Alt2
-		r2 = q.Alt1
-		r3 = q.Corect
-		toReturn = 3
-	case 3:
-		r1 = q.Alt2
-		r2 = q.Corect
-		r3 = q.Alt1
-		toReturn = 2
-	case 4:
-		r1 = q.Corect
-		r2 = q.Alt1
-		r3 = q.Alt2
-		toReturn = 1
-	case 5:
-		r1 = q.Corect
-		r2 = q.Alt2
-		r3 = q.Alt1
-		toReturn = 1
-	}
-
-	message := fmt.Sprintf("QUE\\\\%s\\\\%s\\\\%s\\\\%s", q.Question, r1, r2, r3)
-	for _, val := range players {
-		val.ws.WriteMessage(websocket.TextMessage, []byte(message))
-	}
-
-	return toReturn
-}
-
-func placeAllPlayers(x, y float64) {
-	for i := range players {
-		players[i].position = struct {
-			X float64
-			Y float64
-		}{x, y}
-		players[i].acceleration = struct {
-			X float64
-			Y float64
-		}{0, 0}
-	}
-}
-
-func healAllPlayers() {
-	for i := range players {
-		players[i].health = 100
-		players[i].claimedBombs = []struct {
-			X int
-			Y int
-		}{}
-	}
-}
-
-func clearBlockGrid() {
-	for x := 0; x < len(blockGrid); x++ {
-		for y := 0; y < len(blockGrid[0]); y++ {
-			blockGrid[x][y] = block{}
-		}
-	}
-}
-
-func calculateLevelScore(t time.Duration) {
-	for i := range players {
-		if players[i].winner {
-			players[i].score += (1 - float64(players[i].finishDuration.Milliseconds())/float64(t.Milliseconds())) * maxLevelPoints
-		} else {
-			players[i].score -= nonCompletionPenalty
-		}
-		players[i].winner = false
-	}
-
-	timeAtPodiumAppeared = time.Now()
-	showPodium = true
-}
-
-var showPodium = false
-var timeAtPodiumAppeared = time.Now()
-var currentLevelStartTime = time.Now()
-var triviaAnswer string
-var deltaTime float64
-
-var win *pixelgl.Window
-
-func run() {
-	defer func() {
-		fmt.Println("Please wait while we calculate some scores...")
-		calculateFinalScores()
-	}()
-	go basicAnimator()
-	go explosionManager()
-	go notifyController(time.Millisecond * 500)
-	//* Init window
-	cfg := pixelgl.WindowConfig{
-		Title:     "Goobers!",
-		Bounds:    pixel.R(0, 0, float64(windowX), float64(windowY)),
-		VSync:     true,
-		Maximized: true,
-	}
-	var err error
-	win, err = pixelgl.NewWindow(cfg)
-	if err != nil {
-		panic(err)
-	}
-
-	//* Basic sprites loading
-	// Vignete
-	pic, err := loadPicture(path.Join(wd, "\\assets\\vignete.png"))
-	if err != nil {
-		panic(err)
-	}
-	vignete := pixel.NewSprite(pic, pic.Bounds())
-	// Backgrounds
-	backgroundsDir, err := os.ReadDir(path.Join(wd, "/assets/backgrounds"))
-	if err != nil {
-		panic(err)
-	}
-	var backgrounds []pixel.Sprite
-	for i := 0; i < len(backgroundsDir); i++ {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/backgrounds", fmt.Sprint(i)+".png"))
-		if err != nil {
-			fmt.Println("Failed to load background: ", path.Join(wd, "/assets/backgrounds", fmt.Sprint(i)+".png"))
-			continue
-		}
-
-		backgrounds = append(backgrounds, *pixel.NewSprite(thisIMG, thisIMG.Bounds()))
-	}
-	// Floor
-	var floor pixel.Sprite
-	if true {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks/floor.png"))
-		if err != nil {
-			panic(err)
-		}
-		floor = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-	}
-	// Basic block
-	var basicBlock pixel.Sprite
-	if true {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks/block.png"))
-		if err != nil {
-			panic(err)
-		}
-		basicBlock = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-	}
-	// Lava block
-	var lavaBlock pixel.Sprite
-	if true {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks/lava.png"))
-		if err != nil {
-			panic(err)
-		}
-		lavaBlock = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-	}
-	// Ability block
-	var abilityBlock pixel.Sprite
-	if true {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks/ability.png"))
-		if err != nil {
-			panic(err)
-		}
-		abilityBlock = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-	}
-	// Finish block
-	var finishBlock pixel.Sprite
-	if true {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks/finish.png"))
-		if err != nil {
-			panic(err)
-		}
-		finishBlock = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-	}
-	// Bar
-	statusBar, err := loadPicture(path.Join(wd, "/assets/progress_bar.png"))
-	if err != nil {
-		panic(err)
-	}
-	// Podium
-	var podium pixel.Sprite
-	if true {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/podium.png"))
-		if err != nil {
-			panic(err)
-		}
-		podium = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-	}
-
-	//* Prepare story
-	storyDir, err := os.ReadDir(path.Join(wd, "\\assets\\story"))
-	if err != nil {
-		panic(err)
-	}
-	storyPages := len(storyDir)
-	storyPage := 0
-	storyTimeout := time.Now()
-	//previousTime := time.Now()
-
-	//* Prepare menu
-	inMenu := true
-	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
-	// Get IP
-	privateIP, err := getPrivateIP()
-	if err != nil {
-		panic(err)
-	}
-	IPtext := text.New(pixel.V(0, float64(windowY)*10/100), basicAtlas)
-	IPtext.Color = colornames.Black
-	fmt.Fprintln(IPtext, "Invite Link:")
-	IPtext.Color = colornames.Blue
-	fmt.Fprintln(IPtext, privateIP)
-	// Get text to start game
-	pressToStartText := text.New(pixel.V(0, 0), basicAtlas)
-	pressToStartText.Color = colornames.Red
-	pressToStartTextTimeout := time.Now()
-	pressToStartTextDraw := false
-	fmt.Fprintln(pressToStartText, "Press 'ENTER' to start!")
-	// Get title
-	titleIMG, err := loadPicture(path.Join(wd, "/assets/title.png"))
-	if err != nil {
-		panic(err)
-	}
-	titleSprite := pixel.NewSprite(titleIMG, titleIMG.Bounds())
-	// Get background
-	menuBackgroundID := rand.Intn(len(backgrounds))
-
-	//* Load goobers
-	goobersDir, err := os.ReadDir(path.Join(wd, "/assets/characters"))
-	if err != nil {
-		panic(err)
-	}
-	var goobers []goober
-	for i := 0; i < len(goobersDir)/5; i++ {
-		// IDLE
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_idle.png"))
-		if err != nil {
-			panic(err)
-		}
-		this := goober{
-			idle: *pixel.NewSprite(thisIMG, thisIMG.Bounds()),
-		}
-		// WALKING-RIGHT
-		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_walking-right.png"))
-		if err != nil {
-			panic(err)
-		}
-		this.walking_right = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-		// WALKING-LEFT
-		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_walking-left.png"))
-		if err != nil {
-			panic(err)
-		}
-		this.walking_left = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-		// FALLING
-		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_falling.png"))
-		if err != nil {
-			panic(err)
-		}
-		this.falling = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-		// EXPLODING
-		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_exploding.png"))
-		if err != nil {
-			panic(err)
-		}
-		this.exploding = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
-		goobers = append(goobers, this)
-	}
-
-	//* Load hats
-	hatsDir, err := os.ReadDir(path.Join(wd, "/assets/hats"))
-	if err != nil {
-		panic(err)
-	}
-	var hats []pixel.Sprite
-	for i := range hatsDir {
-		thisIMG, err := loadPicture(path.Join(wd, "/assets/hats", fmt.Sprint(i+1)+".png"))
-		if err != nil {
-			panic(err)
-		}
-		hats = append(hats, *pixel.NewSprite(thisIMG, thisIMG.Bounds()))
-	}
-
-	//*Level counter
-	var currentLevelID = 0
-	var levelDuration = time.Millisecond // preinit at a small number
-	var showProgressBar = true
-
-	//* Deltatime
-	lastTime := time.Now()
-	for !win.Closed() {
-		deltaTime = time.Since(lastTime).Seconds()
-		lastTime = time.Now()
-
-		//* Clear
-		win.Clear(colornames.Skyblue)
-
-		//* Read story
-		if !(storyPage >= storyPages) {
-
-			pic, err := loadPicture(path.Join(wd, "\\assets\\story", fmt.Sprint(storyPage)+".png"))
-			if err != nil {
-				panic(err)
-			}
-			thisStoryPage := pixel.NewSprite(pic, pic.Bounds())
-			xScaleFactor := win.Bounds().W() / thisStoryPage.Frame().W()
-			yScaleFactor := win.Bounds().H() / thisStoryPage.Frame().H()
-			thisStoryPage.Draw(win, pixel.IM.Moved(win.Bounds().Center()).ScaledXY(win.Bounds().Center(), pixel.V(xScaleFactor, yScaleFactor)))
-
-			if time.Since(storyTimeout) >= time.Second*2 {
-				storyTimeout = time.Now()
-				storyPage++
-			}
-			vignete.Draw(win, pixel.IM.Moved(win.Bounds().Center()).Scaled(win.Bounds().Center(), 1))
-			// Key to skip story
-			if win.JustPressed(pixelgl.KeyKPEnter) || win.JustPressed(pixelgl.KeyEnter) {
-				if !(storyPage >= storyPages) {
-					storyPage = storyPages
-					continue
-				}
-			}
-			win.Update()
-			continue
-		}
-
-		//* Open menu
-		if inMenu {
-			// Put background
-			backgrounds[menuBackgroundID].Draw(win, pixel.IM.Moved(win.Bounds().Center()))
-
-			// Get players
-			numOfPlayers := text.New(pixel.V(float64(windowX)*2.5/100, float64(windowY)*10/100), basicAtlas)
-			numOfPlayers.Color = colornames.Black
-			fmt.Fprintln(numOfPlayers, "Number of players:")
-			numOfPlayers.Color = colornames.Blue
-			fmt.Fprintln(numOfPlayers, len(players))
-
-			titleSprite.Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().Center().X, win.Bounds().H()-titleIMG.Bounds().H()/2)))
-			IPtext.Draw(win, pixel.IM.Scaled(IPtext.Orig, 4).Moved(pixel.V(win.Bounds().W()-IPtext.Bounds().W()*4-50, 0)))
-			numOfPlayers.Draw(win, pixel.IM.Scaled(numOfPlayers.Orig, 4))
-
-			if time.Since(pressToStartTextTimeout) >= time.Millisecond*1000 {
-				pressToStartTextTimeout = time.Now()
-				pressToStartTextDraw = !pressToStartTextDraw
-			}
-			if pressToStartTextDraw {
-				pressToStartText.Draw(win, pixel.IM.Scaled(pressToStartText.Orig, 4).Moved(pixel.V((win.Bounds().W()-pressToStartText.Bounds().W()*4)/2, (win.Bounds().H()-pressToStartText.Bounds().H()*4)/2)))
-			}
-
-			if (win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyKPEnter)) && len(players) > 0 {
-				inMenu = false
-			}
-
-			win.Update()
-			continue
-		}
-		//! Render loop
-
-		//* Load level
-		if time.Since(currentLevelStartTime) >= levelDuration || (win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyKPEnter)) {
-			currentLevelID++
-			currentLevelStartTime = time.Now()
-			triviaAnswer = fmt.Sprint(askPlayers())
-
-			levelDuration = basicLevel(currentLevelID - 1)
-			if currentLevelID != 1 {
-				calculateLevelScore(levelDuration)
-			}
-			if !(currentLevelID < numOfLevels) {
-				levelDuration = time.Millisecond
-				currentLevelID = 0
-			}
-
-		}
-
-		gameStarted = true
-		//* Render floor
-		floor.Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().Center().X, 50)))
-
-		//* Render blocks
-		for x := range blockGrid {
-			for y := range blockGrid[0] {
-				var choseBlock pixel.Sprite
-
-				switch blockGrid[x][y].blockType {
-				case "basic":
-					choseBlock = basicBlock
-				case "lava":
-					choseBlock = lavaBlock
-				case "ability":
-					choseBlock = abilityBlock
-				case "finish":
-					choseBlock = finishBlock
-				case "":
-					continue
-				default:
-					fmt.Println("unknown block: " + blockGrid[x][y].blockType)
-					continue
-				}
-
-				blockSizeX := win.Bounds().W() / blocksPerRow
-				blockSizeY := win.Bounds().H()/blocksPerCollumn + 1
-				moveVec := pixel.V((float64(x)+.5)*blockSizeX, (float64(y)+.5)*blockSizeY)
-				choseBlock.Draw(win, pixel.IM.ScaledXY(choseBlock.Frame().Center(), pixel.V(blockSizeX/choseBlock.Frame().W(), blockSizeY/choseBlock.Frame().H())).Moved(moveVec))
-			}
-		}
-
-		//* Render players
-		for _, val := range players {
-			if val.health <= 0 {
-				continue
-			}
-			var toDraw pixel.Sprite
-			switch val.animation {
-			case "idle":
-				toDraw = goobers[val.characterID-1].idle
-			case "walking-right":
-				toDraw = goobers[val.characterID-1].walking_right
-			case "walking-left":
-				toDraw = goobers[val.characterID-1].walking_left
-			case "falling":
-				toDraw = goobers[val.characterID-1].falling
-			case "exploding":
-				toDraw = goobers[val.characterID-1].exploding
-			default:
-			}
-
-			//! This code was copied from block rendering!//
-			blockSizeX := win.Bounds().W() / blocksPerRow
-			blockSizeY := win.Bounds().H()/blocksPerCollumn + 1
-			toDraw.Draw(win, pixel.IM.ScaledXY(toDraw.Frame().Center(), pixel.V(blockSizeX/toDraw.Frame().W(), blockSizeY/toDraw.Frame().H())).Moved(pixel.V(float64(val.position.X), float64(val.position.Y))))
-
-		}
-
-		//* Render hats
-		for _, val := range players {
-			if val.health <= 0 || !val.wearingHat {
-				continue
-			}
-
-			//! This code was copied from block rendering!//
-			blockSizeX := win.Bounds().W() / blocksPerRow
-			blockSizeY := win.Bounds().H()/blocksPerCollumn + 1
-			hats[val.hatID-1].Draw(win, pixel.IM.ScaledXY(hats[val.hatID-1].Frame().Center(), pixel.V(blockSizeX/hats[val.hatID-1].Frame().W(), blockSizeY/hats[val.hatID-1].Frame().H())).Moved(pixel.V(float64(val.position.X), float64(val.position.Y+goobers[val.characterID].idle.Frame().H()/2))))
-		}
-
-		//* Render time
-		if showProgressBar {
-			// Show bar
-			//levelPercent := float64(time.Since(currentLevelStartTime).Milliseconds()) / float64(levelDuration.Milliseconds()) * 100.0
-			//pixel.NewSprite(statusBar, statusBar.Bounds()).Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().Center().X, windowY*90/100)).ScaledXY(win.Bounds().Center(), pixel.V(1-levelPercent/100, 1)))
-			// Show text
-			timeLeft := text.New(pixel.V(0, 0), basicAtlas)
-			timeLeft.Color = colornames.White
-			fmt.Fprintf(timeLeft, "Ending in: %s", (-time.Since(currentLevelStartTime) + levelDuration).Round(time.Millisecond*100).String())
-			timeLeft.Draw(win, pixel.IM.Moved(win.Bounds().Center()).Scaled(win.Bounds().Center(), 4).Moved(pixel.V(-statusBar.Bounds().W()/4, windowY*40/100)))
-
-		}
-
-		//* Render particles
-		for _, val := range particles {
-			if time.Since(val.created) > val.lifespan {
-				continue
-			}
-			val.sprite.Draw(win, pixel.IM.Moved(val.position))
-		}
-
-		//* Render podium
-		if showPodium {
-			if time.Since(timeAtPodiumAppeared) >= podiumDisplayTime {
-				showPodium = false
-			}
-
-			// Find most influential players
-			top1 := 0
-			top2 := 0
-			top3 := 0
-			for i := range players {
-				if players[i].score > players[top1].score {
-					top3 = top2
-					top2 = top1
-					top1 = i
-				} else if players[i].score > players[top2].score {
-					top3 = top2
-					top2 = i
-				} else if players[i].score > players[top3].score {
-					top3 = i
-				}
-			}
-			if len(players) < 2 {
-				top2 = top1
-				top3 = top2
-			} else if len(players) < 3 {
-				top3 = top2
-			}
-
-			// Draw podium
-			podium.Draw(win, pixel.IM.Moved(win.Bounds().Center()))
-
-			// Draw players
-			goobers[players[top3].characterID-1].idle.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(300, 500)))
-			goobers[players[top1].characterID-1].idle.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(960, 500)))
-			goobers[players[top2].characterID-1].idle.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(1620, 500)))
-
-			// Draw scores
-			s3 := text.New(pixel.V(0, 0), basicAtlas)
-			s3.Color = colornames.Orange
-			fmt.Fprintln(s3, players[top3].score)
-			s3.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(300-s3.Bounds().W()*2, 700)))
-
-			s2 := text.New(pixel.V(0, 0), basicAtlas)
-			s2.Color = colornames.Orange
-			fmt.Fprintln(s2, players[top2].score)
-			s2.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(1620-s2.Bounds().W()*2, 700)))
-
-			s1 := text.New(pixel.V(0, 0), basicAtlas)
-			s1.Color = colornames.Orange
-			fmt.Fprintln(s1, players[top1].score)
-			s1.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(960-s1.Bounds().W()*2, 900)))
-
-			// Draw player names
-			n3 := text.New(pixel.V(0, 0), basicAtlas)
-			n3.Color = colornames.White
-			fmt.Fprintln(n3, players[top3].playerName)
-			n3.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(300-n3.Bounds().W()*2, 325)))
-
-			n2 := text.New(pixel.V(0, 0), basicAtlas)
-			n2.Color = colornames.White
-			fmt.Fprintln(n2, players[top2].playerName)
-			n2.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(1620-n2.Bounds().W()*2, 325)))
-
-			n1 := text.New(pixel.V(0, 0), basicAtlas)
-			n1.Color = colornames.White
-			fmt.Fprintln(n1, players[top1].playerName)
-			n1.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(960-n1.Bounds().W()*2, 325)))
-		}
-
-		gravityHandler(deltaTime)
-		movementHandler(deltaTime)
-		//! KEYS
-
-		win.Update()
-
-	}
-}
-
-type finalScore struct {
-	Player string  `json:"Player"`
-	Score  float64 `json:"Score"`
-}
-
-func calculateFinalScores() {
-	gameStarted = false
-	var finalScores []finalScore
-
-	for range players {
-		//* Find biggest number
-		var topID int
-		top := 0.
-		for i := range players {
-			if players[i].score > top {
-				top = players[i].score
-				topID = i
-			}
-		}
-
-		//* Add player to leaderboards
-		finalScores = append(finalScores, finalScore{
-			Player: players[topID].playerName,
-			Score:  players[topID].score,
-		})
-
-		//* Remove player found
-		players = append(players[:topID], players[topID+1:]...)
-	}
-
-	//* Save file
-	data, err := json.Marshal(finalScores)
-	if err != nil {
-		fmt.Println(err)
-		fmt.Println(" \n \n \n ")
-		data = []byte(fmt.Sprint(finalScores))
-	}
-	err = os.WriteFile("scores.json", data, os.ModePerm)
-	if err != nil {
-		fmt.Println(finalScores)
-		panic(err)
-	}
-
-	//* Save logs
-	err = os.WriteFile("logs.txt", []byte(gameLogs), os.ModePerm)
-	if err != nil {
-		panic(err)
-	}
-}
-
-func loadLevelFromFile(levelID int) (time.Duration, struct{ X, Y float64 }) {
-	data, err := os.ReadFile(path.Join(wd, "/levels/normal/", fmt.Sprint(levelID)+".level"))
-	if err != nil {
-		panic(err)
-	}
-	textData := string(data)
-	lines := strings.Split(textData, "\n")[:len(strings.Split(textData, "\n"))-2]
-	for y, valy := range lines[:len(strings.Split(textData, "\n"))-1] {
-		elems := strings.Split(valy, " ")
-		for x, valx := range elems {
-			if x >= len(elems)-1 {
-				continue
-			}
-
-			toPlace := ""
-
-			switch valx {
-			case "N":
-				toPlace = "basic"
-			case "A":
-				toPlace = "ability"
-			case "L":
-				toPlace = "lava"
-			case "F":
-				toPlace = "finish"
-			default:
-				continue
-			}
-
-			blockGrid[x][len(lines)-y].blockType = toPlace
-		}
-	}
-
-	lastLine := strings.Split(textData, "\n")[len(strings.Split(textData, "\n"))-1]
-
-	var secs float64
-	secs, err = strconv.ParseFloat(strings.Split(lastLine, " ")[0], 64)
-	if err != nil {
-		fmt.Println("Failed to load level timer!")
-		return time.Second * 60, struct {
-			X float64
-			Y float64
-		}{blocksPerRow / 2, blocksPerCollumn}
-	}
-
-	var pos struct{ X, Y float64 }
-	pos.X, err = strconv.ParseFloat(strings.Split(lastLine, " ")[1], 64)
-	if err != nil {
-		fmt.Println("Failed to load player position X!")
-		pos.X = blocksPerRow / 2
-	}
-	pos.Y, err = strconv.ParseFloat(strings.Split(lastLine, " ")[2], 64)
-	if err != nil {
-		fmt.Println("Failed to load player position Y!")
-		pos.Y = blocksPerCollumn
-	}
-
-	return time.Second * time.Duration(secs), pos
-}
-
-/*
-func level1() time.Duration {
-	placeAllPlayers(100, 200)
-	healAllPlayers()
-	clearBlockGrid()
-
-	//* Make walls
-	for i := 2; i <= 20; i++ {
-		blockGrid[0][i].blockType = "basic"
-		blockGrid[38][i].blockType = "basic"
-	}
-	for i := 1; i < 39; i++ {
-		blockGrid[i][20].blockType = "basic"
-	}
-
-	//* Actual level
-	blockGrid[1][2].blockType = "basic"
-	blockGrid[2][2].blockType = "basic"
-	blockGrid[3][2].blockType = "basic"
-
-	for i := 4; i < 38; i++ {
-		blockGrid[i][2].blockType = "lava"
-	}
-
-	blockGrid[8][4].blockType = "basic"
-	blockGrid[9][4].blockType = "basic"
-	blockGrid[10][4].blockType = "basic"
-
-	blockGrid[1][7].blockType = "basic"
-	blockGrid[2][7].blockType = "basic"
-	blockGrid[3][7].blockType = "basic"
-
-	blockGrid[12][8].blockType = "basic"
-	blockGrid[13][8].blockType = "basic"
-	blockGrid[14][8].blockType = "basic"
-
-	blockGrid[8][13].blockType = "basic"
-	blockGrid[9][13].blockType = "basic"
-	blockGrid[10][13].blockType = "basic"
-
-	blockGrid[15][10].blockType = "basic"
-	blockGrid[15][14].blockType = "basic"
-
-	for i := 3; i <= 13; i++ {
-		blockGrid[16][i].blockType = "basic"
-	}
-
-	blockGrid[16][14].blockType = "ability"
-
-	blockGrid[37][12].blockType = "finish"
-	blockGrid[36][12].blockType = "finish"
-	blockGrid[35][12].blockType = "finish"
-
-	return time.Second * 60
-}
-
-func level2() time.Duration {
-	placeAllPlayers(100, 150)
-	healAllPlayers()
-	clearBlockGrid()
-
-	//* Make walls
-	for i := 2; i <= 20; i++ {
-		blockGrid[0][i].blockType = "basic"
-		blockGrid[38][i].blockType = "basic"
-	}
-	for i := 1; i < 39; i++ {
-		blockGrid[i][20].blockType = "basic"
-	}
-
-	//* Actual level
-	blockGrid[0][4].blockType = "basic"
-	blockGrid[1][4].blockType = "basic"
-	blockGrid[2][4].blockType = "basic"
-
-	blockGrid[3][4].blockType = "basic"
-	blockGrid[3][5].blockType = "basic"
-	blockGrid[3][6].blockType = "basic"
-	blockGrid[3][7].blockType = "basic"
-	blockGrid[3][8].blockType = "basic"
-	blockGrid[3][9].blockType = "basic"
-	blockGrid[3][10].blockType = "basic"
-	blockGrid[3][11].blockType = "basic"
-	blockGrid[3][12].blockType = "basic"
-	blockGrid[3][13].blockType = "basic"
-
-	blockGrid[7][2].blockType = "basic"
-	blockGrid[7][3].blockType = "basic"
-	blockGrid[7][4].blockType = "basic"
-	blockGrid[7][5].blockType = "basic"
-	blockGrid[7][6].blockType = "basic"
-	blockGrid[7][7].blockType = "basic"
-	blockGrid[7][8].blockType = "basic"
-	blockGrid[7][9].blockType = "basic"
-	blockGrid[7][10].blockType = "basic"
-	blockGrid[7][11].blockType = "basic"
-	blockGrid[7][12].blockType = "basic"
-	blockGrid[7][13].blockType = "basic"
-
-	blockGrid[8][13].blockType = "ability"
-	blockGrid[9][13].blockType = "ability"
-	blockGrid[10][13].blockType = "ability"
-	blockGrid[11][13].blockType = "basic"
-	blockGrid[11][14].blockType = "basic"
-	blockGrid[11][15].blockType = "basic"
-	blockGrid[11][16].blockType = "basic"
-	blockGrid[11][17].blockType = "basic"
-	blockGrid[11][18].blockType = "basic"
-	blockGrid[11][19].blockType = "basic"
-
-	blockGrid[10][2].blockType = "lava"
-	blockGrid[9][2].blockType = "lava"
-	blockGrid[8][2].blockType = "lava"
-
-	blockGrid[11][2].blockType = "basic"
-	blockGrid[11][3].blockType = "basic"
-	blockGrid[11][4].blockType = "basic"
-	blockGrid[11][5].blockType = "basic"
-
-	blockGrid[12][5].blockType = "basic"
-
-	blockGrid[13][5].blockType = "basic"
-	blockGrid[13][6].blockType = "basic"
-	blockGrid[13][7].blockType = "basic"
-	blockGrid[13][8].blockType = "basic"
-	blockGrid[13][9].blockType = "basic"
-	blockGrid[13][10].blockType = "basic"
-	blockGrid[13][11].blockType = "basic"
-
-	blockGrid[12][11].blockType = "basic"
-	blockGrid[12][10].blockType = "basic"
-	blockGrid[11][11].blockType = "basic"
-
-	blockGrid[12][16].blockType = "basic"
-	blockGrid[19][16].blockType = "basic"
-	blockGrid[20][16].blockType = "basic"
-	blockGrid[27][16].blockType = "basic"
-	blockGrid[28][16].blockType = "basic"
-
-	blockGrid[36][16].blockType = "ability"
-	blockGrid[37][16].blockType = "ability"
-
-	blockGrid[35][16].blockType = "basic"
-	blockGrid[34][16].blockType = "basic"
-	blockGrid[34][15].blockType = "basic"
-	blockGrid[35][15].blockType = "basic"
-	blockGrid[35][14].blockType = "basic"
-	blockGrid[35][13].blockType = "basic"
-	blockGrid[35][12].blockType = "basic"
-	blockGrid[35][11].blockType = "basic"
-	blockGrid[35][10].blockType = "basic"
-	blockGrid[35][9].blockType = "basic"
-	blockGrid[35][8].blockType = "basic"
-	blockGrid[35][7].blockType = "basic"
-	blockGrid[35][6].blockType = "basic"
-	blockGrid[35][5].blockType = "basic"
-	blockGrid[35][4].blockType = "basic"
-	blockGrid[35][3].blockType = "basic"
-	blockGrid[35][2].blockType = "basic"
-
-	blockGrid[36][10].blockType = "ability"
-	blockGrid[37][10].blockType = "ability"
-
-	blockGrid[36][4].blockType = "ability"
-	blockGrid[37][4].blockType = "ability"
-
-	blockGrid[36][2].blockType = "finish"
-	blockGrid[37][2].blockType = "finish"
-
-	return time.Second * 90
-}
-
-func level3() time.Duration {
-	placeAllPlayers(100, 200)
-	healAllPlayers()
-	clearBlockGrid()
-
-	//* Make walls
-	for i := 2; i <= 20; i++ {
-		blockGrid[0][i].blockType = "basic"
-		blockGrid[38][i].blockType = "basic"
-	}
-	for i := 1; i < 39; i++ {
-		blockGrid[i][20].blockType = "basic"
-		blockGrid[i][19].blockType = "basic"
-	}
-
-	//* Actual level
-	for i := 1; i < 38; i++ {
-		blockGrid[i][2].blockType = "basic"
-	}
-
-	blockGrid[1][5].blockType = "basic"
-	blockGrid[2][5].blockType = "basic"
-	blockGrid[3][5].blockType = "basic"
-
-	blockGrid[4][5].blockType = "basic"
-	blockGrid[4][6].blockType = "basic"
-	blockGrid[4][7].blockType = "basic"
-
-	blockGrid[3][7].blockType = "basic"
-	blockGrid[2][7].blockType = "basic"
-	blockGrid[1][7].blockType = "basic"
-
-	blockGrid[1][10].blockType = "basic"
-	blockGrid[2][10].blockType = "basic"
-	blockGrid[3][10].blockType = "basic"
-	blockGrid[4][10].blockType = "basic"
-	blockGrid[4][11].blockType = "basic"
-	blockGrid[4][12].blockType = "basic"
-	blockGrid[4][13].blockType = "basic"
-	blockGrid[4][14].blockType = "basic"
-	blockGrid[4][15].blockType = "basic"
-	blockGrid[4][16].blockType = "basic"
-	blockGrid[4][19].blockType = "basic"
-	blockGrid[4][20].blockType = "basic"
-
-	for i := 2; i < 17; i++ {
-		blockGrid[8][i].blockType = "basic"
-	}
-
-	for x := 9; x < 38; x++ {
-		for y := 16; y > 2; y-- {
-			if y%3 != 0 {
-				continue
-			}
-
-			if (x+y/3)%3 != 0 {
-				continue
-			}
-
-			blockGrid[x][y].blockType = "basic"
-		}
-	}
-
-	blockGrid[37][2].blockType = "finish"
-	blockGrid[36][2].blockType = "finish"
-
-	return time.Second * 60
-}
-
-func level4() time.Duration {
-	placeAllPlayers(100, 200)
-	healAllPlayers()
-	clearBlockGrid()
-
-	//* Make walls
-	for i := 2; i <= 20; i++ {
-		blockGrid[0][i].blockType = "basic"
-		blockGrid[38][i].blockType = "basic"
-	}
-	for i := 1; i < 39; i++ {
-		blockGrid[i][20].blockType = "basic"
-	}
-
-	//* Actual level
-	for i := 2; i <= 17; i++ {
-		blockGrid[4][i].blockType = "basic"
-		blockGrid[16][i].blockType = "basic"
-		blockGrid[28][i].blockType = "basic"
-	}
-	for i := 4; i <= 19; i++ {
-		blockGrid[10][i].blockType = "basic"
-		blockGrid[22][i].blockType = "basic"
-		blockGrid[34][i].blockType = "basic"
-	}
-
-	blockGrid[35][2].blockType = "finish"
-	blockGrid[36][2].blockType = "finish"
-	blockGrid[37][2].blockType = "finish"
-
-	for x := 5; x < 38; x++ {
-		for y := 19; y > 6; y-- {
-			if rand.Intn(2) == 0 || y%3 != 0 {
-				continue
-			}
-			blockGrid[x][y].blockType = "ability"
-		}
-	}
-
-	return time.Second * 120
-}*/
-
-func basicLevel(ID int) time.Duration {
-	healAllPlayers()
-	clearBlockGrid()
-
-	blockSizeX := win.Bounds().W() / blocksPerRow
-	blockSizeY := win.Bounds().H()/blocksPerCollumn + 1
-	timer, pos := loadLevelFromFile(ID)
-	placeAllPlayers(pos.X*blockSizeX, pos.Y*blockSizeY)
-
-	return timer
-}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	_ "image/png"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"github.com/gorilla/websocket"
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/font/basicfont"
+
+	"main.go/level"
+)
+
+var wd string
+var maxHats int
+var maxChars int
+var numOfLevels int
+
+const windowX = 1280
+const windowY = 720
+const worldX = 1920
+const worldY = 1080
+const bottomFloor = 100
+const gravity = 1000
+const globalTerminalVelocityX = 1000
+const globalTerminalVelocityY = 1000
+
+// Jumps go about 6 blocks up like they always did, lint-levels prints it.
+// Goobers used to sink into the ground and get pushed out of it, and gravity
+// worked differently, so it takes more power now to keep the levels the same.
+const globalJumpPower = 800.
+const gloablSpeed = 35.
+const groundFriction = 5.
+const airFriction = 4.
+const coyoteTime = time.Millisecond * 100
+const jumpBufferTime = time.Millisecond * 100
+const stompBounce = globalJumpPower * .6
+const stunDuration = time.Second
+const lavaDamage = 100
+const explosionFuse = time.Second * 1
+const explosionDamage = 30
+const explosionPower = 1200
+const explosionRadius = 4.
+const explosionInvulnerability = time.Second
+const chainFuse = time.Millisecond * 150
+const minBombsLeft = 3
+const correctAnswerPoints = 5000.
+const maxLevelPoints = 10000.
+const nonCompletionPenalty = 1000
+const podiumDisplayTime = time.Second * 5
+const pongWait = time.Second * 10
+const writeWait = time.Second * 5
+const statusInterval = time.Millisecond * 100
+const pingPeriod = pongWait * 9 / 10
+const reconnectGracePeriod = time.Second * 30
+
+const blocksPerRow = 39.
+const blocksPerCollumn = 22.
+
+var gameLogs = ""
+
+var headless = flag.Bool("headless", false, "run the game without a window")
+var listenAddr = flag.String("addr", ":80", "address for the controllers server")
+
+type player struct {
+	playerName       string
+	hatID            int
+	characterID      int
+	animation        string
+	wearingHat       bool
+	token            string
+	id               int
+	conn             *controller
+	ready            bool
+	host             bool
+	connected        bool
+	disconnectedAt   time.Time
+	winner           bool
+	score            float64
+	position         struct{ X, Y float64 }
+	velocity         struct{ X, Y float64 }
+	acceleration     struct{ X, Y float64 }
+	terminalVelocity struct{ X, Y float64 }
+	grounded         bool
+	coyoteLeft       time.Duration
+	jumpBufferLeft   time.Duration
+	stunLeft         time.Duration
+	standingOn       string
+	jumpPower        float64
+	speed            float64
+	inventory        [itemKinds]int
+	selectedItem     item
+	boostLeft        time.Duration
+	exploding        bool
+	fuseLeft         time.Duration
+	health           float64
+	finishDuration   time.Duration
+	invulnerableLeft time.Duration
+	claimedItems     []struct{ X, Y int }
+}
+
+type goober struct {
+	idle          pixel.Sprite
+	walking_right pixel.Sprite
+	walking_left  pixel.Sprite
+	falling       pixel.Sprite
+	exploding     pixel.Sprite
+}
+
+type block struct {
+	blockType string
+
+	// Seconds left before a crumbling block is gone, 0 until stood on
+	crumbleLeft float64
+}
+
+type particle struct {
+	// Level clock when the particle showed up, so pausing freezes it
+	created  time.Duration
+	lifespan time.Duration
+	position pixel.Vec
+	sprite   pixel.Sprite
+}
+
+type question struct {
+	Question string `json:"Question"`
+	Corect   string `json:"Correct"`
+	Alt1     string `json:"Alt1"`
+	Alt2     string `json:"Alt2"`
+	Category string `json:"Category"`
+}
+
+// alive reports whether the particle is still shown at level clock now. A
+// new level starts the clock over, which ends the old particles too.
+func (p particle) alive(now time.Duration) bool {
+	return now >= p.created && now-p.created <= p.lifespan
+}
+
+var players []player
+var blockGrid [][]block
+var particles []particle
+var questions []question
+
+func _init() {
+	//* Get wd
+	var err error
+	wd, err = os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	//* Get maxElems
+	characters, err := os.ReadDir(path.Join(wd, "/assets/characters"))
+	if err != nil {
+		panic(err)
+	}
+	hats, err := os.ReadDir(path.Join(wd, "/assets/hats"))
+	if err != nil {
+		panic(err)
+	}
+	maxChars = len(characters)
+	maxHats = len(hats)
+
+	//* Build blockgrid
+	for i := 0; i < blocksPerRow; i++ {
+		var row []block
+		for j := 0; j < blocksPerCollumn; j++ {
+			row = append(row, block{blockType: ""})
+		}
+		blockGrid = append(blockGrid, row)
+	}
+
+	//* Get questions
+	rawQuestions, err := os.ReadFile(path.Join(wd, "questions.json"))
+	if err != nil {
+		panic(err)
+	}
+	err = json.Unmarshal(rawQuestions, &questions)
+	if err != nil {
+		panic(err)
+	}
+
+	//* Get explosion sprite
+	explosionIMG, err := loadPicture(path.Join(wd, "/assets/particles/explosion.png"))
+	if err != nil {
+		panic(err)
+	}
+	explosionSprite = *pixel.NewSprite(explosionIMG, explosionIMG.Bounds())
+
+	//* Get item sprites
+	for i, val := range itemNames {
+		itemIMG, err := loadPicture(path.Join(wd, "/assets/items", val+".png"))
+		if err != nil {
+			panic(err)
+		}
+		itemSprites[i] = *pixel.NewSprite(itemIMG, itemIMG.Bounds())
+	}
+
+	//* Get tiles
+	loadTiles()
+
+	//* Get level packs and num of levels
+	loadPacks()
+}
+
+func readHTML(name string) string {
+	f, err := os.ReadFile(path.Join(wd, "/static/", name+".html"))
+	if err != nil {
+		fmt.Println("Failed to read file ", name)
+		panic(err)
+	}
+	return string(f)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 0 {
+		os.Exit(runTool(flag.Arg(0), flag.Args()[1:]))
+	}
+	_init()
+
+	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("assets"))))
+	http.Handle("/scripts/", http.StripPrefix("/scripts/", http.FileServer(http.Dir("scripts"))))
+	http.Handle("/styles/", http.StripPrefix("/styles/", http.FileServer(http.Dir("styles"))))
+
+	http.HandleFunc("/", handleControls)
+	http.HandleFunc("/ws", handleWebSocket)
+	http.HandleFunc("/editor", handleEditor)
+	http.HandleFunc("/api/", handleEditorAPI)
+
+	go func() {
+		err := http.ListenAndServe(*listenAddr, nil)
+		if err != nil {
+			fmt.Println("All the controllers have been disconected!")
+		}
+	}()
+
+	fmt.Println("Started controllers server!")
+
+	go gameLoop()
+
+	if *headless {
+		runHeadless()
+		return
+	}
+	pixelgl.Run(run)
+}
+
+func handleControls(w http.ResponseWriter, r *http.Request) {
+	html := readHTML("root")
+
+	html = strings.ReplaceAll(html, "'{{ MaxHats }}'", fmt.Sprint(maxHats))
+	html = strings.ReplaceAll(html, "'{{ MaxChars }}'", fmt.Sprint(maxChars))
+
+	fmt.Fprint(w, html)
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Allow any origin
+	},
+}
+
+func findPlayerByToken(token string) int {
+	var ID = -1
+
+	for i := 0; i < len(players); i++ {
+		if players[i].token == token {
+			ID = i
+			break
+		}
+	}
+
+	return ID
+}
+
+// playerByToken is findPlayerByToken that also finds benched goobers.
+func playerByToken(token string) *player {
+	if i := findPlayerByToken(token); i != -1 {
+		return &players[i]
+	}
+	for i := range benchedPlayers {
+		if benchedPlayers[i].token == token {
+			return &benchedPlayers[i]
+		}
+	}
+	return nil
+}
+
+func dist(x float64, y float64, a float64, b float64) float64 {
+	return math.Sqrt((x-a)*(x-a) + (y-b)*(y-b))
+}
+
+// Area the game is simulated in. It is the same on every screen so jumps
+// and levels don't depend on the monitor, the window only scales it.
+var worldBounds = pixel.R(0, 0, worldX, worldY)
+
+func blockSize() (float64, float64) {
+	return blockSizeIn(worldBounds)
+}
+
+// worldMatrix stretches the world over screen.
+func worldMatrix(screen pixel.Rect) pixel.Matrix {
+	return pixel.IM.ScaledXY(pixel.ZV, pixel.V(screen.W()/worldBounds.W(), screen.H()/worldBounds.H()))
+}
+
+func blockSizeIn(bounds pixel.Rect) (float64, float64) {
+	return bounds.W() / blocksPerRow, bounds.H()/blocksPerCollumn + 1
+}
+
+// blockAt returns an empty block for positions outside the grid so the
+// physics never has to index out of range.
+func blockAt(x, y int) block {
+	return blockIn(blockGrid, x, y)
+}
+
+// blockIn is blockAt for any grid.
+func blockIn(grid [][]block, x, y int) block {
+	if x < 0 || x >= len(grid) || y < 0 || y >= len(grid[x]) {
+		return block{}
+	}
+	return grid[x][y]
+}
+
+func loadPicture(path string) (pixel.Picture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return pixel.PictureDataFromImage(img), nil
+}
+
+func getPrivateIP() (string, error) {
+	toReturn := ""
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, iface := range interfaces {
+		// Skip loopback and non-up interfaces
+		if (iface.Flags&net.FlagLoopback == 0) && (iface.Flags&net.FlagUp != 0) {
+			addrs, err := iface.Addrs()
+			if err != nil {
+				return "", err
+			}
+
+			for _, addr := range addrs {
+				ip, _, err := net.ParseCIDR(addr.String())
+				if err != nil {
+					return "", err
+				}
+
+				// Check if the IP address is a private IPv4 address
+				if ip.To4() != nil && (ip.IsLoopback() || ip.IsPrivate()) {
+					toReturn = ip.String()
+				}
+			}
+		}
+	}
+	if toReturn == "" {
+		return "", fmt.Errorf("no private ip address found")
+	} else {
+		return toReturn, nil
+	}
+}
+
+func gravityHandler(deltaTime float64) {
+	for i := range players {
+		//* Tiles the goober is inside of
+		bodyX, bodyY := gridCell(players[i].position)
+		if t := tileOf(blockAt(bodyX, bodyY)); t != nil && !t.Solid {
+			applyTile(t, t.OnTouch, i, bodyX, bodyY, deltaTime)
+		}
+
+		//* Tiles the goober stands on
+		if t, x, y := landGoober(&players[i], blockGrid); t != nil {
+			applyTile(t, t.OnStand, i, x, y, deltaTime)
+			standOnTile(t, i, x, y, deltaTime)
+		}
+
+		jumpGoober(&players[i], deltaTime)
+	}
+}
+
+// landGoober finds out whether p stands on something in grid, and returns
+// the tile when it stands on one.
+func landGoober(p *player, grid [][]block) (t *tileType, x, y int) {
+	p.standingOn = ""
+	p.grounded = false
+	t, x, y, ok := groundUnder(p, grid)
+	if !ok || p.velocity.Y > 0 {
+		return nil, 0, 0
+	}
+	p.grounded = true
+	p.coyoteLeft = coyoteTime
+	p.terminalVelocity.Y = globalTerminalVelocityY
+	if t != nil {
+		p.standingOn = t.Name
+	}
+	return t, x, y
+}
+
+// jumpGoober jumps when p can and pulls it down.
+func jumpGoober(p *player, deltaTime float64) {
+	// Goobers can still jump right after running off an edge, and a jump
+	// pressed right before landing happens once they land
+	if p.jumpBufferLeft > 0 && p.coyoteLeft > 0 {
+		p.velocity.Y = p.jumpPower
+		p.grounded = false
+		p.coyoteLeft = 0
+		p.jumpBufferLeft = 0
+	}
+	tick := time.Duration(deltaTime * float64(time.Second))
+	p.coyoteLeft -= tick
+	p.jumpBufferLeft -= tick
+
+	p.acceleration.Y = -gravity
+}
+
+func movementHandler(deltaTime float64) {
+	for i := range players {
+		stepGoober(&players[i], blockGrid, deltaTime)
+	}
+}
+
+// stepGoober moves p through grid for one tick.
+func stepGoober(p *player, grid [][]block, deltaTime float64) {
+	//* Speed up
+	p.velocity.X += p.acceleration.X * deltaTime
+	p.velocity.Y += p.acceleration.Y * deltaTime
+
+	//* Slow down, ice keeps goobers sliding
+	friction := airFriction
+	if p.grounded {
+		friction = groundFriction
+		if t := tilesByName[p.standingOn]; t != nil {
+			friction *= 1 - t.Slide
+		}
+	}
+	p.velocity.X *= math.Max(0, 1-friction*deltaTime)
+
+	//* Check if everything is ok
+	p.velocity.X = math.Max(-p.terminalVelocity.X, math.Min(p.velocity.X, p.terminalVelocity.X))
+	p.velocity.Y = math.Max(-p.terminalVelocity.Y, math.Min(p.velocity.Y, p.terminalVelocity.Y))
+
+	//* Move, stopping at walls, ceilings and the ground
+	hit := moveGoober(p, grid, p.velocity.X*deltaTime, p.velocity.Y*deltaTime)
+	if hit.wall {
+		p.velocity.X = 0
+	}
+	if hit.ceiling && p.velocity.Y > 0 || hit.landed && p.velocity.Y < 0 {
+		p.velocity.Y = 0
+	}
+}
+
+func basicAnimator() {
+	for i := range players {
+		if players[i].exploding {
+			players[i].animation = "exploding"
+		} else if players[i].velocity.Y < 0 {
+			players[i].animation = "falling"
+		} else if math.Abs(players[i].velocity.X) > 10 {
+			if players[i].velocity.X > 0 {
+				players[i].animation = "walking-right"
+			} else {
+				players[i].animation = "walking-left"
+			}
+		} else {
+			players[i].animation = "idle"
+		}
+	}
+}
+
+var explosionSprite pixel.Sprite
+var itemSprites [itemKinds]pixel.Sprite
+
+func explosionManager(deltaTime float64) {
+	tick := time.Duration(deltaTime * float64(time.Second))
+	for i := range players {
+		if players[i].invulnerableLeft > 0 {
+			players[i].invulnerableLeft -= tick
+		}
+	}
+
+	// Indexes instead of values, a chain reaction can shorten the fuse of a
+	// goober or an explosive further down the list
+	for i := range players {
+		if !players[i].exploding {
+			continue
+		}
+		players[i].fuseLeft -= tick
+		if players[i].fuseLeft > 0 {
+			continue
+		}
+
+		// Make player back
+		players[i].exploding = false
+		players[i].wearingHat = true
+		explode(pixel.V(players[i].position.X, players[i].position.Y), i)
+	}
+	for i := range explosives {
+		if !explosives[i].lit {
+			continue
+		}
+		explosives[i].fuseLeft -= tick
+		if explosives[i].fuseLeft <= 0 {
+			explode(explosives[i].position, -1)
+		}
+	}
+
+	kept := explosives[:0]
+	for _, val := range explosives {
+		if !val.lit || val.fuseLeft > 0 {
+			kept = append(kept, val)
+		}
+	}
+	explosives = kept
+}
+
+// explode sets off a bomb at center, carried by player source or -1 when it
+// was thrown or put down. Goobers close to it are pushed away and hurt, the
+// closer the harder, and breakable blocks around it are gone.
+func explode(center pixel.Vec, source int) {
+	blockSizeX, blockSizeY := blockSize()
+	radius := explosionRadius * blockSizeX
+
+	// Schedule some particles
+	particles = append(particles, particle{
+		created:  levelClock,
+		lifespan: time.Second * 1,
+		position: center,
+		sprite:   explosionSprite,
+	})
+
+	//* Affect players
+	for j := range players {
+		if j == source || players[j].health <= 0 {
+			continue
+		}
+		dx, dy := players[j].position.X-center.X, players[j].position.Y-center.Y
+		d := dist(players[j].position.X, players[j].position.Y, center.X, center.Y)
+		if d >= radius {
+			continue
+		}
+		strength := 1 - d/radius
+
+		// Goobers right on top of the bomb fly straight up
+		dirX, dirY := 0., 1.
+		if d > 0 {
+			dirX, dirY = dx/d, dy/d
+		}
+		players[j].velocity.X += dirX * explosionPower * strength
+		players[j].velocity.Y += dirY * explosionPower * strength
+
+		// One explosion at a time, so a chain reaction doesn't hit the same
+		// goober over and over
+		if players[j].invulnerableLeft <= 0 {
+			players[j].health -= explosionDamage * strength
+			players[j].invulnerableLeft = explosionInvulnerability
+		}
+
+		//* Chain reaction
+		if players[j].exploding && players[j].fuseLeft > chainFuse {
+			players[j].fuseLeft = chainFuse
+		}
+	}
+
+	// Bombs and mines go off too
+	for i := range explosives {
+		e := &explosives[i]
+		if e.lit && e.fuseLeft <= chainFuse || e.position.Sub(center).Len() >= radius {
+			continue
+		}
+		e.lit = true
+		e.fuseLeft = chainFuse
+	}
+
+	//* Break blocks
+	minX, minY := gridCell(struct{ X, Y float64 }{center.X - radius, center.Y - radius})
+	maxX, maxY := gridCell(struct{ X, Y float64 }{center.X + radius, center.Y + radius})
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			t := tileOf(blockAt(x, y))
+			if t == nil || !t.Breakable {
+				continue
+			}
+			if dist((float64(x)+.5)*blockSizeX, (float64(y)+.5)*blockSizeY, center.X, center.Y) < radius {
+				blockGrid[x][y] = block{}
+			}
+		}
+	}
+}
+
+// blinking reports whether p is hidden this frame, goobers blink while
+// explosions can't hurt them.
+func blinking(p player) bool {
+	const blinkInterval = time.Millisecond * 100
+	return p.invulnerableLeft > 0 && p.invulnerableLeft/blinkInterval%2 == 1
+}
+
+// askPlayers sends a random question from category, or from all of them if
+// the category is empty or has no questions.
+func askPlayers(category string) int {
+	var pool []question
+	for _, val := range questions {
+		if val.Category == category {
+			pool = append(pool, val)
+		}
+	}
+	if len(pool) == 0 {
+		pool = questions
+	}
+	q := pool[rand.Intn(len(pool))]
+	var r1, r2, r3 string
+	var toReturn int
+	switch rand.Intn(6) {
+	case 0:
+		r1 = q.Alt1
+		r2 = q.Alt2
+		r3 = q.Corect
+		toReturn = 3
+	case 1:
+		r1 = q.Alt1
+		r2 = q.Corect
+		r3 = q.Alt2
+		toReturn = 2
+	case 2:
+		r1 = q.Alt2
+		r2 = q.Alt1
+		r3 = q.Corect
+		toReturn = 3
+	case 3:
+		r1 = q.Alt2
+		r2 = q.Corect
+		r3 = q.Alt1
+		toReturn = 2
+	case 4:
+		r1 = q.Corect
+		r2 = q.Alt1
+		r3 = q.Alt2
+		toReturn = 1
+	case 5:
+		r1 = q.Corect
+		r2 = q.Alt2
+		r3 = q.Alt1
+		toReturn = 1
+	}
+
+	broadcast(newQuestionMessage(q.Question, [3]string{r1, r2, r3}))
+
+	return toReturn
+}
+
+// placeAllPlayers puts the players on the spawn points, taking turns when
+// there are more players than spawns.
+func placeAllPlayers(spawns []level.Spawn) {
+	blockSizeX, blockSizeY := blockSize()
+	for i := range players {
+		spawn := spawns[i%len(spawns)]
+		players[i].position = struct {
+			X float64
+			Y float64
+		}{spawn.X * blockSizeX, spawn.Y * blockSizeY}
+		players[i].velocity = struct {
+			X float64
+			Y float64
+		}{0, 0}
+		players[i].acceleration = struct {
+			X float64
+			Y float64
+		}{0, 0}
+	}
+}
+
+func healAllPlayers() {
+	for i := range players {
+		players[i].health = 100
+		players[i].invulnerableLeft = 0
+		players[i].stunLeft = 0
+		players[i].claimedItems = []struct {
+			X int
+			Y int
+		}{}
+	}
+}
+
+func clearBlockGrid() {
+	for x := 0; x < len(blockGrid); x++ {
+		for y := 0; y < len(blockGrid[0]); y++ {
+			blockGrid[x][y] = block{}
+		}
+	}
+	crumblingBlocks = nil
+	explosives = nil
+}
+
+func calculateLevelScore(t time.Duration) {
+	for i := range players {
+		if players[i].winner {
+			players[i].score += (1 - float64(players[i].finishDuration.Milliseconds())/float64(t.Milliseconds())) * maxLevelPoints
+		} else {
+			players[i].score -= nonCompletionPenalty
+		}
+		players[i].winner = false
+	}
+}
+
+var triviaAnswer int
+
+// Level counter
+var currentLevelID = 0
+var currentLevel *level.Level
+var levelDuration = time.Millisecond // preinit at a small number
+
+// loadNextLevel loads the level after the current one and asks the trivia
+// question that goes with it. Levels that fail to load are skipped.
+func loadNextLevel() {
+	levelClock = 0
+	levelDuration = 0
+	currentLevel = nil
+
+	for currentLevelID < numOfLevels {
+		l, err := basicLevel(currentLevelID)
+		currentLevelID++
+		if err != nil {
+			fmt.Println("Failed to load level: ", err)
+			continue
+		}
+		currentLevel = l
+		levelDuration = currentPack.Duration(l)
+		triviaAnswer = askPlayers(l.TriviaCategory)
+		return
+	}
+}
+
+var win *pixelgl.Window
+
+func run() {
+	defer queryGame(saveLogs)
+	//* Init window
+	cfg := pixelgl.WindowConfig{
+		Title:     "Goobers!",
+		Bounds:    pixel.R(0, 0, float64(windowX), float64(windowY)),
+		VSync:     true,
+		Maximized: true,
+	}
+	var err error
+	win, err = pixelgl.NewWindow(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	//* Basic sprites loading
+	// Vignete
+	pic, err := loadPicture(path.Join(wd, "/assets/vignete.png"))
+	if err != nil {
+		panic(err)
+	}
+	vignete := pixel.NewSprite(pic, pic.Bounds())
+	// Backgrounds
+	backgroundsDir, err := os.ReadDir(path.Join(wd, "/assets/backgrounds"))
+	if err != nil {
+		panic(err)
+	}
+	var backgrounds []pixel.Sprite
+	for i := 0; i < len(backgroundsDir); i++ {
+		thisIMG, err := loadPicture(path.Join(wd, "/assets/backgrounds", fmt.Sprint(i)+".png"))
+		if err != nil {
+			fmt.Println("Failed to load background: ", path.Join(wd, "/assets/backgrounds", fmt.Sprint(i)+".png"))
+			continue
+		}
+
+		backgrounds = append(backgrounds, *pixel.NewSprite(thisIMG, thisIMG.Bounds()))
+	}
+	// Floor
+	var floor pixel.Sprite
+	if true {
+		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks/floor.png"))
+		if err != nil {
+			panic(err)
+		}
+		floor = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+	}
+	// Blocks, by tile name
+	blockSprites := map[string]pixel.Sprite{}
+	for _, val := range tileTypes {
+		thisIMG, err := loadPicture(path.Join(wd, "/assets/blocks", val.Sprite))
+		if err != nil {
+			fmt.Println("Failed to load block: ", path.Join(wd, "/assets/blocks", val.Sprite))
+			continue
+		}
+		blockSprites[val.Name] = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+	}
+	// Bar
+	statusBar, err := loadPicture(path.Join(wd, "/assets/progress_bar.png"))
+	if err != nil {
+		panic(err)
+	}
+	// Podium
+	var podium pixel.Sprite
+	if true {
+		thisIMG, err := loadPicture(path.Join(wd, "/assets/podium.png"))
+		if err != nil {
+			panic(err)
+		}
+		podium = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+	}
+
+	//* Prepare story
+	storyDir, err := os.ReadDir(path.Join(wd, "/assets/story"))
+	if err != nil {
+		panic(err)
+	}
+	storyPages := len(storyDir)
+	storyPage := 0
+	storyTimeout := time.Now()
+	storyFinished := false
+	//previousTime := time.Now()
+
+	//* Prepare menu
+	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
+	// Get IP
+	privateIP, err := getPrivateIP()
+	if err != nil {
+		panic(err)
+	}
+	IPtext := text.New(pixel.V(0, float64(windowY)*10/100), basicAtlas)
+	IPtext.Color = colornames.Black
+	fmt.Fprintln(IPtext, "Invite Link:")
+	IPtext.Color = colornames.Blue
+	fmt.Fprintln(IPtext, privateIP)
+	// Get text to start game
+	pressToStartText := text.New(pixel.V(0, 0), basicAtlas)
+	pressToStartText.Color = colornames.Red
+	pressToStartTextTimeout := time.Now()
+	pressToStartTextDraw := false
+	fmt.Fprintln(pressToStartText, "Press 'ENTER' or start from the host phone!")
+	// Get title
+	titleIMG, err := loadPicture(path.Join(wd, "/assets/title.png"))
+	if err != nil {
+		panic(err)
+	}
+	titleSprite := pixel.NewSprite(titleIMG, titleIMG.Bounds())
+	// Get background
+	menuBackgroundID := rand.Intn(len(backgrounds))
+
+	//* Load goobers
+	goobersDir, err := os.ReadDir(path.Join(wd, "/assets/characters"))
+	if err != nil {
+		panic(err)
+	}
+	var goobers []goober
+	for i := 0; i < len(goobersDir)/5; i++ {
+		// IDLE
+		thisIMG, err := loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_idle.png"))
+		if err != nil {
+			panic(err)
+		}
+		this := goober{
+			idle: *pixel.NewSprite(thisIMG, thisIMG.Bounds()),
+		}
+		// WALKING-RIGHT
+		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_walking-right.png"))
+		if err != nil {
+			panic(err)
+		}
+		this.walking_right = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+		// WALKING-LEFT
+		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_walking-left.png"))
+		if err != nil {
+			panic(err)
+		}
+		this.walking_left = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+		// FALLING
+		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_falling.png"))
+		if err != nil {
+			panic(err)
+		}
+		this.falling = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+		// EXPLODING
+		thisIMG, err = loadPicture(path.Join(wd, "/assets/characters", fmt.Sprint(i+1)+"_exploding.png"))
+		if err != nil {
+			panic(err)
+		}
+		this.exploding = *pixel.NewSprite(thisIMG, thisIMG.Bounds())
+		goobers = append(goobers, this)
+	}
+
+	//* Load hats
+	hatsDir, err := os.ReadDir(path.Join(wd, "/assets/hats"))
+	if err != nil {
+		panic(err)
+	}
+	var hats []pixel.Sprite
+	for i := range hatsDir {
+		thisIMG, err := loadPicture(path.Join(wd, "/assets/hats", fmt.Sprint(i+1)+".png"))
+		if err != nil {
+			panic(err)
+		}
+		hats = append(hats, *pixel.NewSprite(thisIMG, thisIMG.Bounds()))
+	}
+
+	//* Block rendering, shared with the editor
+	// offset is where the bottom left corner of screen is in the world, only
+	// the blocks on screen are drawn
+	drawBlocks := func(grid [][]block, offset pixel.Vec, screen pixel.Rect) {
+		minX, minY, maxX, maxY := visibleBlocks(grid, offset, screen)
+		for x := minX; x < maxX; x++ {
+			for y := minY; y < maxY; y++ {
+				if grid[x][y].blockType == "" {
+					continue
+				}
+				choseBlock, ok := blockSprites[grid[x][y].blockType]
+				if !ok {
+					continue
+				}
+
+				blockSizeX, blockSizeY := blockSizeIn(screen)
+				moveVec := pixel.V((float64(x)+.5)*blockSizeX, (float64(y)+.5)*blockSizeY).Sub(offset)
+				if left := grid[x][y].crumbleLeft; left > 0 {
+					// Shake before falling apart
+					moveVec.X += math.Sin(left*40) * blockSizeX / 20
+				}
+				choseBlock.Draw(win, pixel.IM.ScaledXY(choseBlock.Frame().Center(), pixel.V(blockSizeX/choseBlock.Frame().W(), blockSizeY/choseBlock.Frame().H())).Moved(moveVec))
+			}
+		}
+	}
+
+	// The floor goes on forever, drawn once per screen
+	drawFloor := func(offset pixel.Vec, screen pixel.Rect) {
+		floorWidth := floor.Frame().W()
+		for x := -math.Mod(offset.X, floorWidth); x < screen.W(); x += floorWidth {
+			floor.Draw(win, pixel.IM.Moved(pixel.V(x+floorWidth/2, 50-offset.Y)))
+		}
+	}
+
+	var editor *levelEditor
+	var cam camera
+	lastFrame := time.Now()
+
+	var showProgressBar = true
+
+	for !win.Closed() {
+		//* Everything drawn this frame comes from the same snapshot
+		snap := currentSnapshot()
+		frameTime := time.Since(lastFrame).Seconds()
+		lastFrame = time.Now()
+
+		//* Clear
+		win.Clear(colornames.Skyblue)
+
+		//* Read story
+		if snap.state == stateStory {
+			// Wait for the game to open the lobby
+			if storyPage >= storyPages {
+				if !storyFinished {
+					storyFinished = true
+					sendCommand(finishStory)
+				}
+				win.Update()
+				continue
+			}
+
+			pic, err := loadPicture(path.Join(wd, "/assets/story", fmt.Sprint(storyPage)+".png"))
+			if err != nil {
+				panic(err)
+			}
+			thisStoryPage := pixel.NewSprite(pic, pic.Bounds())
+			xScaleFactor := win.Bounds().W() / thisStoryPage.Frame().W()
+			yScaleFactor := win.Bounds().H() / thisStoryPage.Frame().H()
+			thisStoryPage.Draw(win, pixel.IM.Moved(win.Bounds().Center()).ScaledXY(win.Bounds().Center(), pixel.V(xScaleFactor, yScaleFactor)))
+
+			if time.Since(storyTimeout) >= time.Second*2 {
+				storyTimeout = time.Now()
+				storyPage++
+			}
+			vignete.Draw(win, pixel.IM.Moved(win.Bounds().Center()).Scaled(win.Bounds().Center(), 1))
+			// Key to skip story
+			if win.JustPressed(pixelgl.KeyKPEnter) || win.JustPressed(pixelgl.KeyEnter) {
+				if !(storyPage >= storyPages) {
+					storyPage = storyPages
+					continue
+				}
+			}
+			win.Update()
+			continue
+		}
+
+		//* Open menu
+		if snap.state == stateLobby {
+			// Put background
+			backgrounds[menuBackgroundID].Draw(win, pixel.IM.Moved(win.Bounds().Center()))
+
+			// Get players
+			numOfPlayers := text.New(pixel.V(float64(windowX)*2.5/100, float64(windowY)*10/100), basicAtlas)
+			numOfPlayers.Color = colornames.Black
+			fmt.Fprintln(numOfPlayers, "Number of players:")
+			numOfPlayers.Color = colornames.Blue
+			fmt.Fprintln(numOfPlayers, connectedPlayers(snap.players))
+
+			// Roster
+			roster := text.New(pixel.V(float64(windowX)*2.5/100, float64(windowY)*75/100), basicAtlas)
+			roster.Color = colornames.Black
+			fmt.Fprintf(roster, "Levels: %s (%s)\n", snap.packName, snap.playlist)
+			for _, val := range snap.players {
+				if !val.connected {
+					continue
+				}
+				roster.Color = colornames.Gray
+				if val.ready {
+					roster.Color = colornames.Green
+				}
+				line := val.playerName
+				if val.host {
+					line += " (host)"
+				}
+				if val.ready {
+					line += " - ready"
+				}
+				fmt.Fprintln(roster, line)
+			}
+
+			titleSprite.Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().Center().X, win.Bounds().H()-titleIMG.Bounds().H()/2)))
+			IPtext.Draw(win, pixel.IM.Scaled(IPtext.Orig, 4).Moved(pixel.V(win.Bounds().W()-IPtext.Bounds().W()*4-50, 0)))
+			numOfPlayers.Draw(win, pixel.IM.Scaled(numOfPlayers.Orig, 4))
+			roster.Draw(win, pixel.IM.Scaled(roster.Orig, 3))
+
+			if time.Since(pressToStartTextTimeout) >= time.Millisecond*1000 {
+				pressToStartTextTimeout = time.Now()
+				pressToStartTextDraw = !pressToStartTextDraw
+			}
+			if pressToStartTextDraw {
+				pressToStartText.Draw(win, pixel.IM.Scaled(pressToStartText.Orig, 4).Moved(pixel.V((win.Bounds().W()-pressToStartText.Bounds().W()*4)/2, (win.Bounds().H()-pressToStartText.Bounds().H()*4)/2)))
+			}
+
+			if (win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyKPEnter)) && connectedPlayers(snap.players) > 0 {
+				sendCommand(startGame)
+			}
+
+			if win.JustPressed(pixelgl.KeyE) {
+				queryGame(func() { editor = openEditor() })
+			}
+
+			win.Update()
+			continue
+		}
+
+		//* Level editor
+		if snap.state == stateEditor && editor != nil {
+			if win.JustPressed(pixelgl.KeyEscape) && editor.discard() {
+				sendCommand(closeEditor)
+			}
+			editor.update(win)
+
+			drawFloor(editor.offset(win), win.Bounds())
+			drawBlocks(editor.grid(), editor.offset(win), win.Bounds())
+
+			// Spawns
+			spawnSprite := goobers[0].idle
+			blockSizeX, blockSizeY := blockSizeIn(win.Bounds())
+			for _, pos := range editor.spawnPositions(win) {
+				spawnSprite.Draw(win, pixel.IM.ScaledXY(spawnSprite.Frame().Center(), pixel.V(blockSizeX/spawnSprite.Frame().W(), blockSizeY/spawnSprite.Frame().H())).Moved(pos))
+			}
+
+			status := text.New(pixel.V(0, 0), basicAtlas)
+			status.Color = colornames.Black
+			fmt.Fprintln(status, editor.status())
+			status.Draw(win, pixel.IM.Scaled(status.Orig, 2).Moved(pixel.V(10, win.Bounds().H()-30)))
+
+			win.Update()
+			continue
+		}
+
+		//* Keyboard goober for testing levels
+		if snap.state == stateTestPlay {
+			stick := 0.
+			if win.Pressed(pixelgl.KeyLeft) || win.Pressed(pixelgl.KeyA) {
+				stick -= 100
+			}
+			if win.Pressed(pixelgl.KeyRight) || win.Pressed(pixelgl.KeyD) {
+				stick += 100
+			}
+			jump := win.JustPressed(pixelgl.KeyUp) || win.JustPressed(pixelgl.KeyW) || win.JustPressed(pixelgl.KeySpace)
+			use := win.JustPressed(pixelgl.KeyDown) || win.JustPressed(pixelgl.KeyS)
+			keyboard.updateInput(func(in *playerInput) {
+				in.stickX = stick
+				in.jump = in.jump || jump
+				in.use = in.use || use
+			})
+
+			if win.JustPressed(pixelgl.KeyTab) {
+				sendCommand(func() {
+					if i := findPlayerByToken(testPlayerToken); i != -1 {
+						cycleItem(i)
+					}
+				})
+			}
+
+			if win.JustPressed(pixelgl.KeyEscape) {
+				sendCommand(stopTestPlay)
+			}
+		}
+
+		//* Show final scores
+		if snap.state == stateResults {
+			backgrounds[menuBackgroundID].Draw(win, pixel.IM.Moved(win.Bounds().Center()))
+
+			standings := append([]player(nil), snap.players...)
+			sort.SliceStable(standings, func(i, j int) bool {
+				return standings[i].score > standings[j].score
+			})
+			results := text.New(pixel.V(0, 0), basicAtlas)
+			results.Color = colornames.Red
+			fmt.Fprintln(results, "Game over!")
+			results.Color = colornames.Black
+			for i, val := range standings {
+				fmt.Fprintf(results, "%d. %s - %.0f\n", i+1, val.playerName, val.score)
+			}
+			results.Draw(win, pixel.IM.Scaled(results.Orig, 4).Moved(pixel.V((win.Bounds().W()-results.Bounds().W()*4)/2, win.Bounds().H()*80/100)))
+
+			win.Update()
+			continue
+		}
+		//! Render loop
+
+		//* Skip level
+		if win.JustPressed(pixelgl.KeyEnter) || win.JustPressed(pixelgl.KeyKPEnter) {
+			sendCommand(endLevel)
+		}
+
+		//* Pause
+		if win.JustPressed(pixelgl.KeyP) {
+			sendCommand(togglePause)
+		}
+
+		//* Render level background
+		if snap.level != nil && snap.level.Background >= 0 && snap.level.Background < len(backgrounds) {
+			backgrounds[snap.level.Background].Draw(win, pixel.IM.Moved(win.Bounds().Center()))
+		}
+
+		//* Camera
+		// The level is drawn in world coordinates and scaled to the window
+		win.SetMatrix(worldMatrix(win.Bounds()))
+		cam.follow(snap, worldBounds, frameTime)
+		offset := cam.position
+
+		//* Render floor
+		drawFloor(offset, worldBounds)
+
+		//* Render blocks
+		drawBlocks(snap.blockGrid, offset, worldBounds)
+
+		//* Render players
+		for _, val := range snap.players {
+			if val.health <= 0 || !val.connected || blinking(val) {
+				continue
+			}
+			var toDraw pixel.Sprite
+			switch val.animation {
+			case "idle":
+				toDraw = goobers[val.characterID-1].idle
+			case "walking-right":
+				toDraw = goobers[val.characterID-1].walking_right
+			case "walking-left":
+				toDraw = goobers[val.characterID-1].walking_left
+			case "falling":
+				toDraw = goobers[val.characterID-1].falling
+			case "exploding":
+				toDraw = goobers[val.characterID-1].exploding
+			default:
+			}
+
+			//! This code was copied from block rendering!//
+			blockSizeX, blockSizeY := blockSize()
+			matrix := pixel.IM.ScaledXY(toDraw.Frame().Center(), pixel.V(blockSizeX/toDraw.Frame().W(), blockSizeY/toDraw.Frame().H())).Moved(pixel.V(float64(val.position.X), float64(val.position.Y)).Sub(offset))
+
+			// Stunned goobers are greyed out until they can move again, boosted
+			// ones glow
+			if val.stunLeft > 0 {
+				toDraw.DrawColorMask(win, matrix, colornames.Gray)
+			} else if val.boostLeft > 0 {
+				toDraw.DrawColorMask(win, matrix, colornames.Gold)
+			} else {
+				toDraw.Draw(win, matrix)
+			}
+		}
+
+		//* Render hats
+		for _, val := range snap.players {
+			if val.health <= 0 || !val.wearingHat || !val.connected || blinking(val) {
+				continue
+			}
+
+			//! This code was copied from block rendering!//
+			blockSizeX, blockSizeY := blockSize()
+			hats[val.hatID-1].Draw(win, pixel.IM.ScaledXY(hats[val.hatID-1].Frame().Center(), pixel.V(blockSizeX/hats[val.hatID-1].Frame().W(), blockSizeY/hats[val.hatID-1].Frame().H())).Moved(pixel.V(float64(val.position.X), float64(val.position.Y+goobers[val.characterID].idle.Frame().H()/2)).Sub(offset)))
+		}
+
+		//* Render bombs and mines
+		for _, val := range snap.explosives {
+			blockSizeX, blockSizeY := blockSize()
+			sprite := itemSprites[val.kind]
+			size := pixel.V(explosiveSize*blockSizeX/sprite.Frame().W(), explosiveSize*blockSizeY/sprite.Frame().H())
+			sprite.Draw(win, pixel.IM.ScaledXY(pixel.ZV, size).Moved(val.position.Sub(offset)))
+		}
+
+		//* Render particles
+		for _, val := range snap.particles {
+			if !val.alive(snap.levelClock) {
+				continue
+			}
+			val.sprite.Draw(win, pixel.IM.Moved(val.position.Sub(offset)))
+		}
+
+		// Everything after this is drawn over the window
+		win.SetMatrix(pixel.IM)
+
+		//* Render level number
+		if snap.state == stateLevelIntro {
+			levelText := text.New(pixel.V(0, 0), basicAtlas)
+			levelText.Color = colornames.White
+			fmt.Fprintf(levelText, "Level %d/%d\n", snap.levelID, snap.numOfLevels)
+			if snap.level != nil {
+				fmt.Fprintln(levelText, snap.level.Name)
+			}
+			levelText.Color = colornames.Orange
+			fmt.Fprintf(levelText, "%d", countdownLeft(snap.stateClock))
+			levelText.Draw(win, pixel.IM.Scaled(levelText.Orig, 6).Moved(pixel.V((win.Bounds().W()-levelText.Bounds().W()*6)/2, (win.Bounds().H()-levelText.Bounds().H()*6)/2)))
+		}
+
+		//* Render time
+		if showProgressBar && (snap.state == statePlaying || snap.state == stateTestPlay) {
+			// Show bar
+			//levelPercent := float64(snap.levelClock.Milliseconds()) / float64(snap.levelDuration.Milliseconds()) * 100.0
+			//pixel.NewSprite(statusBar, statusBar.Bounds()).Draw(win, pixel.IM.Moved(pixel.V(win.Bounds().Center().X, windowY*90/100)).ScaledXY(win.Bounds().Center(), pixel.V(1-levelPercent/100, 1)))
+			// Show text
+			timeLeft := text.New(pixel.V(0, 0), basicAtlas)
+			timeLeft.Color = colornames.White
+			fmt.Fprintf(timeLeft, "Ending in: %s", (snap.levelDuration - snap.levelClock).Round(time.Millisecond*100).String())
+			timeLeft.Draw(win, pixel.IM.Moved(win.Bounds().Center()).Scaled(win.Bounds().Center(), 4).Moved(pixel.V(-statusBar.Bounds().W()/4, windowY*40/100)))
+
+		}
+
+		//* Render the items of the keyboard goober
+		if snap.state == stateTestPlay {
+			for _, val := range snap.players {
+				if val.conn != keyboard {
+					continue
+				}
+				items := text.New(pixel.V(0, 0), basicAtlas)
+				items.Color = colornames.White
+				for i, name := range itemNames {
+					if item(i) == val.selectedItem {
+						fmt.Fprintf(items, "[%s %d]  ", name, val.inventory[i])
+					} else {
+						fmt.Fprintf(items, "%s %d  ", name, val.inventory[i])
+					}
+				}
+				fmt.Fprint(items, "(Tab switches)")
+				items.Draw(win, pixel.IM.Scaled(items.Orig, 2).Moved(pixel.V(10, 10)))
+			}
+		}
+
+		//* Render podium
+		if snap.state == statePodium && len(snap.players) > 0 {
+			// Find most influential players
+			top1 := 0
+			top2 := 0
+			top3 := 0
+			for i := range snap.players {
+				if snap.players[i].score > snap.players[top1].score {
+					top3 = top2
+					top2 = top1
+					top1 = i
+				} else if snap.players[i].score > snap.players[top2].score {
+					top3 = top2
+					top2 = i
+				} else if snap.players[i].score > snap.players[top3].score {
+					top3 = i
+				}
+			}
+			if len(snap.players) < 2 {
+				top2 = top1
+				top3 = top2
+			} else if len(snap.players) < 3 {
+				top3 = top2
+			}
+
+			// Draw podium
+			podium.Draw(win, pixel.IM.Moved(win.Bounds().Center()))
+
+			// Draw players
+			goobers[snap.players[top3].characterID-1].idle.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(300, 500)))
+			goobers[snap.players[top1].characterID-1].idle.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(960, 500)))
+			goobers[snap.players[top2].characterID-1].idle.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(1620, 500)))
+
+			// Draw scores
+			s3 := text.New(pixel.V(0, 0), basicAtlas)
+			s3.Color = colornames.Orange
+			fmt.Fprintln(s3, snap.players[top3].score)
+			s3.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(300-s3.Bounds().W()*2, 700)))
+
+			s2 := text.New(pixel.V(0, 0), basicAtlas)
+			s2.Color = colornames.Orange
+			fmt.Fprintln(s2, snap.players[top2].score)
+			s2.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(1620-s2.Bounds().W()*2, 700)))
+
+			s1 := text.New(pixel.V(0, 0), basicAtlas)
+			s1.Color = colornames.Orange
+			fmt.Fprintln(s1, snap.players[top1].score)
+			s1.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(960-s1.Bounds().W()*2, 900)))
+
+			// Draw player names
+			n3 := text.New(pixel.V(0, 0), basicAtlas)
+			n3.Color = colornames.White
+			fmt.Fprintln(n3, snap.players[top3].playerName)
+			n3.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(300-n3.Bounds().W()*2, 325)))
+
+			n2 := text.New(pixel.V(0, 0), basicAtlas)
+			n2.Color = colornames.White
+			fmt.Fprintln(n2, snap.players[top2].playerName)
+			n2.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(1620-n2.Bounds().W()*2, 325)))
+
+			n1 := text.New(pixel.V(0, 0), basicAtlas)
+			n1.Color = colornames.White
+			fmt.Fprintln(n1, snap.players[top1].playerName)
+			n1.Draw(win, pixel.IM.Scaled(pixel.V(0, 0), 4).Moved(pixel.V(960-n1.Bounds().W()*2, 325)))
+		}
+
+		//* Render pause
+		if snap.paused {
+			pausedText := text.New(pixel.V(0, 0), basicAtlas)
+			pausedText.Color = colornames.Red
+			fmt.Fprintln(pausedText, "PAUSED")
+			pausedText.Draw(win, pixel.IM.Scaled(pausedText.Orig, 8).Moved(pixel.V((win.Bounds().W()-pausedText.Bounds().W()*8)/2, (win.Bounds().H()-pausedText.Bounds().H()*8)/2)))
+		}
+
+		//! KEYS
+
+		win.Update()
+
+	}
+}
+
+type finalScore struct {
+	Player string  `json:"Player"`
+	Score  float64 `json:"Score"`
+}
+
+func calculateFinalScores() {
+	var finalScores []finalScore
+
+	//* Add players to leaderboards
+	for i := range players {
+		finalScores = append(finalScores, finalScore{
+			Player: players[i].playerName,
+			Score:  players[i].score,
+		})
+	}
+
+	//* Biggest score first
+	sort.SliceStable(finalScores, func(i, j int) bool {
+		return finalScores[i].Score > finalScores[j].Score
+	})
+
+	//* Save file
+	data, err := json.Marshal(finalScores)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println(" \n \n \n ")
+		data = []byte(fmt.Sprint(finalScores))
+	}
+	err = os.WriteFile("scores.json", data, os.ModePerm)
+	if err != nil {
+		fmt.Println(finalScores)
+		panic(err)
+	}
+
+	saveLogs()
+}
+
+// saveLogs writes the game logs to logs.txt. Scores are saved once a game
+// reaches the results, not here, the lobby already reset them by then.
+func saveLogs() {
+	err := os.WriteFile("logs.txt", []byte(gameLogs), os.ModePerm)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func loadLevelFromFile(levelID int) (*level.Level, error) {
+	l, err := loadPlaylistLevel(currentPack, playlist[levelID])
+	if err != nil {
+		return nil, err
+	}
+	applyLevel(l)
+	return l, nil
+}
+
+// applyLevel makes blockGrid the size of l and puts its tiles in it.
+func applyLevel(l *level.Level) {
+	crumblingBlocks = nil
+	blockGrid = make([][]block, l.Width())
+	for x := range blockGrid {
+		blockGrid[x] = make([]block, l.Height())
+		for y := range blockGrid[x] {
+			blockGrid[x][y] = blockFor(l.Tile(x, y))
+		}
+	}
+}
+
+/*
+func level1() time.Duration {
+	placeAllPlayers(100, 200)
+	healAllPlayers()
+	clearBlockGrid()
+
+	//* Make walls
+	for i := 2; i <= 20; i++ {
+		blockGrid[0][i].blockType = "basic"
+		blockGrid[38][i].blockType = "basic"
+	}
+	for i := 1; i < 39; i++ {
+		blockGrid[i][20].blockType = "basic"
+	}
+
+	//* Actual level
+	blockGrid[1][2].blockType = "basic"
+	blockGrid[2][2].blockType = "basic"
+	blockGrid[3][2].blockType = "basic"
+
+	for i := 4; i < 38; i++ {
+		blockGrid[i][2].blockType = "lava"
+	}
+
+	blockGrid[8][4].blockType = "basic"
+	blockGrid[9][4].blockType = "basic"
+	blockGrid[10][4].blockType = "basic"
+
+	blockGrid[1][7].blockType = "basic"
+	blockGrid[2][7].blockType = "basic"
+	blockGrid[3][7].blockType = "basic"
+
+	blockGrid[12][8].blockType = "basic"
+	blockGrid[13][8].blockType = "basic"
+	blockGrid[14][8].blockType = "basic"
+
+	blockGrid[8][13].blockType = "basic"
+	blockGrid[9][13].blockType = "basic"
+	blockGrid[10][13].blockType = "basic"
+
+	blockGrid[15][10].blockType = "basic"
+	blockGrid[15][14].blockType = "basic"
+
+	for i := 3; i <= 13; i++ {
+		blockGrid[16][i].blockType = "basic"
+	}
+
+	blockGrid[16][14].blockType = "ability"
+
+	blockGrid[37][12].blockType = "finish"
+	blockGrid[36][12].blockType = "finish"
+	blockGrid[35][12].blockType = "finish"
+
+	return time.Second * 60
+}
+
+func level2() time.Duration {
+	placeAllPlayers(100, 150)
+	healAllPlayers()
+	clearBlockGrid()
+
+	//* Make walls
+	for i := 2; i <= 20; i++ {
+		blockGrid[0][i].blockType = "basic"
+		blockGrid[38][i].blockType = "basic"
+	}
+	for i := 1; i < 39; i++ {
+		blockGrid[i][20].blockType = "basic"
+	}
+
+	//* Actual level
+	blockGrid[0][4].blockType = "basic"
+	blockGrid[1][4].blockType = "basic"
+	blockGrid[2][4].blockType = "basic"
+
+	blockGrid[3][4].blockType = "basic"
+	blockGrid[3][5].blockType = "basic"
+	blockGrid[3][6].blockType = "basic"
+	blockGrid[3][7].blockType = "basic"
+	blockGrid[3][8].blockType = "basic"
+	blockGrid[3][9].blockType = "basic"
+	blockGrid[3][10].blockType = "basic"
+	blockGrid[3][11].blockType = "basic"
+	blockGrid[3][12].blockType = "basic"
+	blockGrid[3][13].blockType = "basic"
+
+	blockGrid[7][2].blockType = "basic"
+	blockGrid[7][3].blockType = "basic"
+	blockGrid[7][4].blockType = "basic"
+	blockGrid[7][5].blockType = "basic"
+	blockGrid[7][6].blockType = "basic"
+	blockGrid[7][7].blockType = "basic"
+	blockGrid[7][8].blockType = "basic"
+	blockGrid[7][9].blockType = "basic"
+	blockGrid[7][10].blockType = "basic"
+	blockGrid[7][11].blockType = "basic"
+	blockGrid[7][12].blockType = "basic"
+	blockGrid[7][13].blockType = "basic"
+
+	blockGrid[8][13].blockType = "ability"
+	blockGrid[9][13].blockType = "ability"
+	blockGrid[10][13].blockType = "ability"
+	blockGrid[11][13].blockType = "basic"
+	blockGrid[11][14].blockType = "basic"
+	blockGrid[11][15].blockType = "basic"
+	blockGrid[11][16].blockType = "basic"
+	blockGrid[11][17].blockType = "basic"
+	blockGrid[11][18].blockType = "basic"
+	blockGrid[11][19].blockType = "basic"
+
+	blockGrid[10][2].blockType = "lava"
+	blockGrid[9][2].blockType = "lava"
+	blockGrid[8][2].blockType = "lava"
+
+	blockGrid[11][2].blockType = "basic"
+	blockGrid[11][3].blockType = "basic"
+	blockGrid[11][4].blockType = "basic"
+	blockGrid[11][5].blockType = "basic"
+
+	blockGrid[12][5].blockType = "basic"
+
+	blockGrid[13][5].blockType = "basic"
+	blockGrid[13][6].blockType = "basic"
+	blockGrid[13][7].blockType = "basic"
+	blockGrid[13][8].blockType = "basic"
+	blockGrid[13][9].blockType = "basic"
+	blockGrid[13][10].blockType = "basic"
+	blockGrid[13][11].blockType = "basic"
+
+	blockGrid[12][11].blockType = "basic"
+	blockGrid[12][10].blockType = "basic"
+	blockGrid[11][11].blockType = "basic"
+
+	blockGrid[12][16].blockType = "basic"
+	blockGrid[19][16].blockType = "basic"
+	blockGrid[20][16].blockType = "basic"
+	blockGrid[27][16].blockType = "basic"
+	blockGrid[28][16].blockType = "basic"
+
+	blockGrid[36][16].blockType = "ability"
+	blockGrid[37][16].blockType = "ability"
+
+	blockGrid[35][16].blockType = "basic"
+	blockGrid[34][16].blockType = "basic"
+	blockGrid[34][15].blockType = "basic"
+	blockGrid[35][15].blockType = "basic"
+	blockGrid[35][14].blockType = "basic"
+	blockGrid[35][13].blockType = "basic"
+	blockGrid[35][12].blockType = "basic"
+	blockGrid[35][11].blockType = "basic"
+	blockGrid[35][10].blockType = "basic"
+	blockGrid[35][9].blockType = "basic"
+	blockGrid[35][8].blockType = "basic"
+	blockGrid[35][7].blockType = "basic"
+	blockGrid[35][6].blockType = "basic"
+	blockGrid[35][5].blockType = "basic"
+	blockGrid[35][4].blockType = "basic"
+	blockGrid[35][3].blockType = "basic"
+	blockGrid[35][2].blockType = "basic"
+
+	blockGrid[36][10].blockType = "ability"
+	blockGrid[37][10].blockType = "ability"
+
+	blockGrid[36][4].blockType = "ability"
+	blockGrid[37][4].blockType = "ability"
+
+	blockGrid[36][2].blockType = "finish"
+	blockGrid[37][2].blockType = "finish"
+
+	return time.Second * 90
+}
+
+func level3() time.Duration {
+	placeAllPlayers(100, 200)
+	healAllPlayers()
+	clearBlockGrid()
+
+	//* Make walls
+	for i := 2; i <= 20; i++ {
+		blockGrid[0][i].blockType = "basic"
+		blockGrid[38][i].blockType = "basic"
+	}
+	for i := 1; i < 39; i++ {
+		blockGrid[i][20].blockType = "basic"
+		blockGrid[i][19].blockType = "basic"
+	}
+
+	//* Actual level
+	for i := 1; i < 38; i++ {
+		blockGrid[i][2].blockType = "basic"
+	}
+
+	blockGrid[1][5].blockType = "basic"
+	blockGrid[2][5].blockType = "basic"
+	blockGrid[3][5].blockType = "basic"
+
+	blockGrid[4][5].blockType = "basic"
+	blockGrid[4][6].blockType = "basic"
+	blockGrid[4][7].blockType = "basic"
+
+	blockGrid[3][7].blockType = "basic"
+	blockGrid[2][7].blockType = "basic"
+	blockGrid[1][7].blockType = "basic"
+
+	blockGrid[1][10].blockType = "basic"
+	blockGrid[2][10].blockType = "basic"
+	blockGrid[3][10].blockType = "basic"
+	blockGrid[4][10].blockType = "basic"
+	blockGrid[4][11].blockType = "basic"
+	blockGrid[4][12].blockType = "basic"
+	blockGrid[4][13].blockType = "basic"
+	blockGrid[4][14].blockType = "basic"
+	blockGrid[4][15].blockType = "basic"
+	blockGrid[4][16].blockType = "basic"
+	blockGrid[4][19].blockType = "basic"
+	blockGrid[4][20].blockType = "basic"
+
+	for i := 2; i < 17; i++ {
+		blockGrid[8][i].blockType = "basic"
+	}
+
+	for x := 9; x < 38; x++ {
+		for y := 16; y > 2; y-- {
+			if y%3 != 0 {
+				continue
+			}
+
+			if (x+y/3)%3 != 0 {
+				continue
+			}
+
+			blockGrid[x][y].blockType = "basic"
+		}
+	}
+
+	blockGrid[37][2].blockType = "finish"
+	blockGrid[36][2].blockType = "finish"
+
+	return time.Second * 60
+}
+
+func level4() time.Duration {
+	placeAllPlayers(100, 200)
+	healAllPlayers()
+	clearBlockGrid()
+
+	//* Make walls
+	for i := 2; i <= 20; i++ {
+		blockGrid[0][i].blockType = "basic"
+		blockGrid[38][i].blockType = "basic"
+	}
+	for i := 1; i < 39; i++ {
+		blockGrid[i][20].blockType = "basic"
+	}
+
+	//* Actual level
+	for i := 2; i <= 17; i++ {
+		blockGrid[4][i].blockType = "basic"
+		blockGrid[16][i].blockType = "basic"
+		blockGrid[28][i].blockType = "basic"
+	}
+	for i := 4; i <= 19; i++ {
+		blockGrid[10][i].blockType = "basic"
+		blockGrid[22][i].blockType = "basic"
+		blockGrid[34][i].blockType = "basic"
+	}
+
+	blockGrid[35][2].blockType = "finish"
+	blockGrid[36][2].blockType = "finish"
+	blockGrid[37][2].blockType = "finish"
+
+	for x := 5; x < 38; x++ {
+		for y := 19; y > 6; y-- {
+			if rand.Intn(2) == 0 || y%3 != 0 {
+				continue
+			}
+			blockGrid[x][y].blockType = "ability"
+		}
+	}
+
+	return time.Second * 120
+}*/
+
+func basicLevel(ID int) (*level.Level, error) {
+	healAllPlayers()
+	clearBlockGrid()
+
+	l, err := loadLevelFromFile(ID)
+	if err != nil {
+		return nil, err
+	}
+	placeAllPlayers(l.Spawns)
+
+	return l, nil
+}
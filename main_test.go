@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/pixel"
+	"main.go/level"
+)
+
+func TestChainReaction(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 20; x++ {
+		l.SetTile(x, 4, level.Basic)
+	}
+	l.SetTile(7, 5, level.Cracked)
+	testWorld(t, l)
+	blockSizeX, blockSizeY := blockSize()
+
+	// The first goober goes off next tick, the second one and the bomb are
+	// close enough to be set off early and the third one is far away
+	players = []player{testGoober(5.5, 5), testGoober(8.5, 5), testGoober(18.5, 5)}
+	players[0].exploding, players[0].fuseLeft = true, tickDuration
+	players[1].exploding, players[1].fuseLeft = true, explosionFuse
+	explosives = []explosive{{kind: itemBomb, position: pixel.V(4*blockSizeX, 5.5*blockSizeY), lit: true, fuseLeft: explosionFuse}}
+
+	// Fuses set off by the chain still burn down in the same tick
+	explosionManager(tickDeltaTime)
+	if players[0].exploding || players[0].health != 100 {
+		t.Errorf("first goober: exploding %v, health %.0f", players[0].exploding, players[0].health)
+	}
+	if players[1].fuseLeft > chainFuse || players[1].health >= 100 {
+		t.Errorf("second goober: fuse %s, health %.0f", players[1].fuseLeft, players[1].health)
+	}
+	if players[2].health != 100 {
+		t.Errorf("goober far away was hurt")
+	}
+	if explosives[0].fuseLeft > chainFuse {
+		t.Errorf("bomb fuse is %s, want at most %s", explosives[0].fuseLeft, chainFuse)
+	}
+	if blockGrid[7][5].blockType != "" {
+		t.Errorf("cracked block is still there")
+	}
+
+	for tick := time.Duration(0); tick <= chainFuse; tick += tickDuration {
+		explosionManager(tickDeltaTime)
+	}
+	if players[1].exploding {
+		t.Errorf("second goober never went off")
+	}
+	if len(explosives) > 0 {
+		t.Errorf("bomb never went off")
+	}
+}
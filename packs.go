@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"main.go/level"
+	"main.go/levelgen"
+)
+
+// Level packs are the folders in /levels/, see level.LoadPack
+var packs []*level.Pack
+var currentPack *level.Pack
+
+const defaultPack = "normal"
+
+// How the levels of the pack are picked for a game
+const (
+	playlistOrdered  = "ordered"
+	playlistShuffled = "shuffled"
+	playlistRandom   = "random"
+
+	// New levels from the generator, see generatedPrefix
+	playlistGenerated = "generated"
+)
+
+// Playlist entries starting with this are made by the generator when they
+// are played: "generated:hard", or "generated:hard:42" for the same level
+// every time. Packs can list them like level files.
+const generatedPrefix = "generated:"
+
+const defaultDifficulty = "normal"
+
+var playlistMode = playlistOrdered
+
+// Number of levels played in random mode
+var playlistCount = 5
+
+// How hard the levels of a generated playlist are, see levelgen.Difficulties
+var playlistDifficulty = defaultDifficulty
+
+// Level files played this game, in order
+var playlist []string
+
+func loadPacks() {
+	dirs, err := os.ReadDir(path.Join(wd, "/levels/"))
+	if err != nil {
+		panic(err)
+	}
+	packs = nil
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		p, err := level.LoadPack(path.Join(wd, "/levels/", dir.Name()))
+		if err != nil {
+			fmt.Println("Failed to load level pack: ", err)
+			continue
+		}
+		packs = append(packs, p)
+	}
+	if len(packs) == 0 {
+		panic("no level packs in /levels/")
+	}
+
+	if err := selectPack(defaultPack); err != nil {
+		selectPack(packID(packs[0]))
+	}
+}
+
+// packID is the folder name, which is how controllers pick a pack.
+func packID(p *level.Pack) string {
+	return path.Base(p.Dir)
+}
+
+func selectPack(ID string) error {
+	for _, val := range packs {
+		if packID(val) == ID {
+			currentPack = val
+			buildPlaylist()
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level pack %q", ID)
+}
+
+// selectPlaylist changes how levels are picked. An empty difficulty keeps
+// the one picked before.
+func selectPlaylist(mode string, count int, difficulty string) error {
+	if _, ok := levelgen.Difficulties[difficulty]; difficulty != "" && !ok {
+		return fmt.Errorf("unknown difficulty %q", difficulty)
+	}
+	switch mode {
+	case playlistOrdered, playlistShuffled:
+	case playlistRandom, playlistGenerated:
+		if count <= 0 {
+			return fmt.Errorf("random playlists need at least one level")
+		}
+		playlistCount = count
+		if difficulty != "" {
+			playlistDifficulty = difficulty
+		}
+	default:
+		return fmt.Errorf("unknown playlist %q", mode)
+	}
+	playlistMode = mode
+	buildPlaylist()
+	return nil
+}
+
+// buildPlaylist picks the levels of the next game from the current pack.
+func buildPlaylist() {
+	levels := currentPack.Levels
+	playlist = append([]string(nil), levels...)
+
+	switch playlistMode {
+	case playlistShuffled:
+		rand.Shuffle(len(playlist), func(i, j int) {
+			playlist[i], playlist[j] = playlist[j], playlist[i]
+		})
+	case playlistRandom:
+		playlist = nil
+		for _, i := range rand.Perm(len(levels)) {
+			if len(playlist) >= playlistCount {
+				break
+			}
+			playlist = append(playlist, levels[i])
+		}
+	case playlistGenerated:
+		playlist = nil
+		for i := 0; i < playlistCount; i++ {
+			playlist = append(playlist, generatedPrefix+playlistDifficulty)
+		}
+	}
+
+	numOfLevels = len(playlist)
+	rosterChanged = true
+}
+
+// addLevelToPack adds a new level file to the end of the pack and saves the
+// manifest.
+func addLevelToPack(p *level.Pack, file string) error {
+	for _, val := range p.Levels {
+		if val == file {
+			return nil
+		}
+	}
+	p.Levels = append(append([]string(nil), p.Levels...), file)
+	if p == currentPack {
+		buildPlaylist()
+	}
+	return p.Save()
+}
+
+func playlistName() string {
+	switch playlistMode {
+	case playlistRandom:
+		return fmt.Sprintf("%d %s", numOfLevels, playlistMode)
+	case playlistGenerated:
+		return fmt.Sprintf("%d %s %s", numOfLevels, playlistDifficulty, playlistMode)
+	}
+	return playlistMode
+}
+
+func isGenerated(name string) bool {
+	return strings.HasPrefix(name, generatedPrefix)
+}
+
+// loadPlaylistLevel loads the level called name from p, or generates it.
+func loadPlaylistLevel(p *level.Pack, name string) (*level.Level, error) {
+	if !isGenerated(name) {
+		return p.Load(name)
+	}
+
+	spec := strings.Split(strings.TrimPrefix(name, generatedPrefix), ":")
+	d, ok := levelgen.Difficulties[spec[0]]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown difficulty %q", name, spec[0])
+	}
+	seed := rand.Int63()
+	if len(spec) > 1 {
+		var err error
+		if seed, err = strconv.ParseInt(spec[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("%s: bad seed: %w", name, err)
+		}
+	}
+	return levelgen.Generate(seed, d, levelRules())
+}
+
+// levelFiles are the levels of p that are files, without generated ones.
+func levelFiles(p *level.Pack) []string {
+	var files []string
+	for _, val := range p.Levels {
+		if !isGenerated(val) {
+			files = append(files, val)
+		}
+	}
+	return files
+}
+
+func newPackInfos() []packInfo {
+	var infos []packInfo
+	for _, val := range packs {
+		infos = append(infos, packInfo{ID: packID(val), Name: val.Name, Levels: len(val.Levels)})
+	}
+	return infos
+}
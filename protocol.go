@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Controllers say hello with the newest version they speak and the game
+// answers with the version both sides will use.
+const protocolVersion = 2
+const minProtocolVersion = 1
+
+// Version 2 added binary input frames
+const binaryInputVersion = 2
+
+const maxNameLength = 25
+
+// Messages sent by controllers
+const (
+	msgHello  = "hello"
+	msgJoin   = "join"
+	msgResume = "resume"
+	msgStick  = "stick"
+	msgButton = "button"
+	msgAnswer = "answer"
+	msgItem   = "item"
+
+	// Lobby
+	msgReady    = "ready"
+	msgHost     = "host"
+	msgStart    = "start"
+	msgKick     = "kick"
+	msgPack     = "pack"
+	msgPlaylist = "playlist"
+	msgBumping  = "bumping"
+	msgPause    = "pause"
+)
+
+// Messages sent by the game
+const (
+	msgWelcome  = "welcome"
+	msgSession  = "session"
+	msgStatus   = "status"
+	msgQuestion = "question"
+	msgError    = "error"
+	msgRoster   = "roster"
+	msgState    = "state"
+)
+
+// Error codes controllers can react to
+const (
+	errBadMessage     = "bad_message"
+	errBadVersion     = "bad_version"
+	errNoHello        = "no_hello"
+	errUnknownSession = "unknown_session"
+	errNotHost        = "not_host"
+	errKicked         = "kicked"
+)
+
+const (
+	buttonGreen = "green"
+	buttonRed   = "red"
+)
+
+// clientMessage is every message a controller can send. Type decides which
+// of the other fields are used.
+type clientMessage struct {
+	Type string `json:"type"`
+
+	// hello
+	Version int `json:"version,omitempty"`
+
+	// join
+	Name      string `json:"name,omitempty"`
+	Hat       int    `json:"hat,omitempty"`
+	Character int    `json:"character,omitempty"`
+
+	// resume
+	Token string `json:"token,omitempty"`
+
+	// stick, both go from -100 to 100
+	X float64 `json:"x,omitempty"`
+	Y float64 `json:"y,omitempty"`
+
+	// button
+	Button string `json:"button,omitempty"`
+
+	// answer, 1 to 3
+	Answer int `json:"answer,omitempty"`
+
+	// item, the name of the item the red button uses
+	Item string `json:"item,omitempty"`
+
+	// ready
+	Ready bool `json:"ready,omitempty"`
+
+	// host
+	PIN string `json:"pin,omitempty"`
+
+	// kick, the ID from the roster
+	Player int `json:"player,omitempty"`
+
+	// pack, the pack ID from the roster
+	Pack string `json:"pack,omitempty"`
+
+	// playlist, Count is only used by random playlists and Difficulty by
+	// generated ones
+	Mode       string `json:"mode,omitempty"`
+	Count      int    `json:"count,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+
+	// bumping, false lets goobers walk through each other
+	Bumping bool `json:"bumping,omitempty"`
+
+	// pause, false resumes
+	Paused bool `json:"paused,omitempty"`
+}
+
+func decodeClientMessage(data []byte) (clientMessage, error) {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, err
+	}
+	return msg, msg.validate()
+}
+
+func (msg clientMessage) validate() error {
+	switch msg.Type {
+	case msgHello:
+		if msg.Version <= 0 {
+			return errors.New("hello needs a version")
+		}
+	case msgJoin:
+		if msg.Name == "" || len([]rune(msg.Name)) > maxNameLength {
+			return fmt.Errorf("name must have between 1 and %d characters", maxNameLength)
+		}
+		if msg.Hat < 1 || msg.Hat > maxHats {
+			return fmt.Errorf("hat must be between 1 and %d", maxHats)
+		}
+		if msg.Character < 1 || msg.Character > maxChars {
+			return fmt.Errorf("character must be between 1 and %d", maxChars)
+		}
+	case msgResume:
+		if msg.Token == "" {
+			return errors.New("resume needs a token")
+		}
+	case msgStick:
+		if math.IsNaN(msg.X) || math.IsNaN(msg.Y) || math.Abs(msg.X) > 100 || math.Abs(msg.Y) > 100 {
+			return errors.New("stick must be between -100 and 100")
+		}
+	case msgButton:
+		if msg.Button != buttonGreen && msg.Button != buttonRed {
+			return fmt.Errorf("unknown button %q", msg.Button)
+		}
+	case msgAnswer:
+		if msg.Answer < 1 || msg.Answer > 3 {
+			return errors.New("answer must be between 1 and 3")
+		}
+	case msgItem:
+		if _, ok := itemByName(msg.Item); !ok {
+			return fmt.Errorf("unknown item %q", msg.Item)
+		}
+	case msgReady, msgStart, msgBumping, msgPause:
+	case msgHost:
+		if msg.PIN == "" {
+			return errors.New("host needs a PIN")
+		}
+	case msgKick:
+		if msg.Player <= 0 {
+			return errors.New("kick needs a player")
+		}
+	case msgPack:
+		if msg.Pack == "" {
+			return errors.New("pack needs an ID")
+		}
+	case msgPlaylist:
+		if msg.Mode == "" || msg.Count < 0 {
+			return errors.New("playlist needs a mode")
+		}
+	default:
+		return fmt.Errorf("unknown message type %q", msg.Type)
+	}
+	return nil
+}
+
+// Binary input frames are 6 bytes:
+//
+//	kind (1) | sequence (2, big endian) | stick x (1) | stick y (1) | buttons (1)
+//
+// The stick goes from -100 to 100 on both axes.
+const inputFrameKind = 1
+const inputFrameSize = 6
+
+const (
+	inputButtonGreen = 1 << iota
+	inputButtonRed
+)
+
+type inputFrame struct {
+	sequence uint16
+	stickX   int8
+	stickY   int8
+	buttons  byte
+}
+
+func decodeInputFrame(data []byte) (inputFrame, error) {
+	var frame inputFrame
+	if len(data) != inputFrameSize {
+		return frame, fmt.Errorf("input frame must be %d bytes, got %d", inputFrameSize, len(data))
+	}
+	if data[0] != inputFrameKind {
+		return frame, fmt.Errorf("unknown binary frame kind %d", data[0])
+	}
+
+	frame.sequence = binary.BigEndian.Uint16(data[1:3])
+	frame.stickX = int8(data[3])
+	frame.stickY = int8(data[4])
+	frame.buttons = data[5]
+
+	if frame.stickX < -100 || frame.stickX > 100 || frame.stickY < -100 || frame.stickY > 100 {
+		return frame, errors.New("stick must be between -100 and 100")
+	}
+	return frame, nil
+}
+
+// decodeLegacyMessage turns the old space separated messages (NEW, RESUME,
+// BAL, BTN and RSP) into a clientMessage. It will go away once every
+// controller speaks JSON.
+func decodeLegacyMessage(data []byte) (clientMessage, error) {
+	var msg clientMessage
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return msg, errors.New("empty message")
+	}
+
+	var err error
+	switch fields[0] {
+	case "NEW":
+		if len(fields) < 4 {
+			return msg, errors.New("NEW needs a hat, a character and a name")
+		}
+		msg.Type = msgJoin
+		if msg.Hat, err = strconv.Atoi(fields[1]); err != nil {
+			return msg, err
+		}
+		if msg.Character, err = strconv.Atoi(fields[2]); err != nil {
+			return msg, err
+		}
+		msg.Name = strings.Join(fields[3:], " ")
+	case "RESUME":
+		if len(fields) != 2 {
+			return msg, errors.New("RESUME needs a token")
+		}
+		msg.Type = msgResume
+		msg.Token = fields[1]
+	case "BAL":
+		if len(fields) < 2 {
+			return msg, errors.New("BAL needs a position")
+		}
+		msg.Type = msgStick
+		if msg.X, err = strconv.ParseFloat(fields[1], 64); err != nil {
+			return msg, err
+		}
+		if len(fields) > 2 {
+			if msg.Y, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return msg, err
+			}
+		}
+	case "BTN":
+		if len(fields) != 2 {
+			return msg, errors.New("BTN needs a button")
+		}
+		msg.Type = msgButton
+		msg.Button = strings.ToLower(fields[1])
+	case "RSP":
+		if len(fields) != 2 {
+			return msg, errors.New("RSP needs an answer")
+		}
+		msg.Type = msgAnswer
+		if msg.Answer, err = strconv.Atoi(fields[1]); err != nil {
+			return msg, err
+		}
+	default:
+		return msg, fmt.Errorf("unknown message %q", fields[0])
+	}
+
+	return msg, msg.validate()
+}
+
+// serverMessage is anything the game sends to a controller. legacy returns
+// the same message in the old format, or nothing if it has no equivalent.
+type serverMessage interface {
+	legacy() []string
+}
+
+type welcomeMessage struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+func newWelcomeMessage(version int) welcomeMessage {
+	return welcomeMessage{Type: msgWelcome, Version: version}
+}
+
+func (msg welcomeMessage) legacy() []string {
+	return nil
+}
+
+type sessionMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+	ID    int    `json:"id"`
+}
+
+func newSessionMessage(token string, ID int) sessionMessage {
+	return sessionMessage{Type: msgSession, Token: token, ID: ID}
+}
+
+func (msg sessionMessage) legacy() []string {
+	return []string{"TOK\\\\" + msg.Token}
+}
+
+type statusMessage struct {
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	Bombs  int     `json:"bombs"`
+	Health float64 `json:"health"`
+
+	// How many of each item the player has and the one the red button uses
+	Items    map[string]int `json:"items"`
+	Selected string         `json:"selected"`
+}
+
+func newStatusMessage(p player) statusMessage {
+	msg := statusMessage{
+		Type:     msgStatus,
+		Name:     p.playerName,
+		Bombs:    p.inventory[itemBomb],
+		Health:   p.health,
+		Items:    map[string]int{},
+		Selected: itemNames[p.selectedItem],
+	}
+	for i, val := range p.inventory {
+		msg.Items[itemNames[i]] = val
+	}
+	return msg
+}
+
+func (msg statusMessage) legacy() []string {
+	return []string{
+		fmt.Sprintf("BOM\\\\%s\\\\%d", msg.Name, msg.Bombs),
+		fmt.Sprintf("HEL\\\\%s\\\\%f", msg.Name, msg.Health),
+	}
+}
+
+type questionMessage struct {
+	Type     string    `json:"type"`
+	Question string    `json:"question"`
+	Answers  [3]string `json:"answers"`
+}
+
+func newQuestionMessage(q string, answers [3]string) questionMessage {
+	return questionMessage{Type: msgQuestion, Question: q, Answers: answers}
+}
+
+func (msg questionMessage) legacy() []string {
+	return []string{fmt.Sprintf("QUE\\\\%s\\\\%s\\\\%s\\\\%s", msg.Question, msg.Answers[0], msg.Answers[1], msg.Answers[2])}
+}
+
+// gameStateMessage tells controllers which part of the game is on screen.
+type gameStateMessage struct {
+	Type   string `json:"type"`
+	State  string `json:"state"`
+	Level  int    `json:"level"`
+	Levels int    `json:"levels"`
+	Paused bool   `json:"paused"`
+
+	// 3, 2, 1 during the level intro
+	Countdown int `json:"countdown"`
+}
+
+func (msg gameStateMessage) legacy() []string {
+	return nil
+}
+
+type rosterEntry struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Hat       int    `json:"hat"`
+	Character int    `json:"character"`
+	Ready     bool   `json:"ready"`
+	Host      bool   `json:"host"`
+	Connected bool   `json:"connected"`
+}
+
+type packInfo struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Levels int    `json:"levels"`
+}
+
+type rosterMessage struct {
+	Type    string        `json:"type"`
+	Lobby   bool          `json:"lobby"`
+	Players []rosterEntry `json:"players"`
+
+	// Level pack and playlist picked by the host
+	Pack       string     `json:"pack"`
+	Packs      []packInfo `json:"packs"`
+	Playlist   string     `json:"playlist"`
+	Count      int        `json:"count"`
+	Difficulty string     `json:"difficulty"`
+	Bumping    bool       `json:"bumping"`
+}
+
+func newRosterMessage(list []player, lobby bool) rosterMessage {
+	msg := rosterMessage{Type: msgRoster, Lobby: lobby, Players: []rosterEntry{}}
+	for _, val := range list {
+		msg.Players = append(msg.Players, rosterEntry{
+			ID:        val.id,
+			Name:      val.playerName,
+			Hat:       val.hatID,
+			Character: val.characterID,
+			Ready:     val.ready,
+			Host:      val.host,
+			Connected: val.connected,
+		})
+	}
+	return msg
+}
+
+func (msg rosterMessage) legacy() []string {
+	return nil
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newErrorMessage(code string, err error) errorMessage {
+	return errorMessage{Type: msgError, Code: code, Message: err.Error()}
+}
+
+func (msg errorMessage) legacy() []string {
+	// Old controllers only know how to handle a lost session
+	if msg.Code != errUnknownSession {
+		return nil
+	}
+	return []string{"ERR\\\\" + msg.Message}
+}
+
+// closeMessage makes the writer close the connection once everything
+// queued before it has been written.
+type closeMessage struct{}
+
+func (msg closeMessage) legacy() []string {
+	return nil
+}
+
+// encodeServerMessage returns the frames msg is sent as, either a single
+// JSON frame or the old string frames for legacy controllers.
+func encodeServerMessage(msg serverMessage, legacy bool) ([][]byte, error) {
+	if legacy {
+		var frames [][]byte
+		for _, val := range msg.legacy() {
+			frames = append(frames, []byte(val))
+		}
+		return frames, nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{data}, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	maxHats, maxChars = 3, 2
+
+	tests := []struct {
+		name string
+		msg  clientMessage
+		ok   bool
+	}{
+		{"hello", clientMessage{Type: msgHello, Version: 1}, true},
+		{"hello without version", clientMessage{Type: msgHello}, false},
+		{"join", clientMessage{Type: msgJoin, Name: "bob", Hat: 3, Character: 2}, true},
+		{"join without name", clientMessage{Type: msgJoin, Hat: 1, Character: 1}, false},
+		{"join with long name", clientMessage{Type: msgJoin, Name: "abcdefghijklmnopqrstuvwxyz", Hat: 1, Character: 1}, false},
+		{"join with unknown hat", clientMessage{Type: msgJoin, Name: "bob", Hat: 4, Character: 1}, false},
+		{"join with unknown character", clientMessage{Type: msgJoin, Name: "bob", Hat: 1, Character: 0}, false},
+		{"resume", clientMessage{Type: msgResume, Token: "abc"}, true},
+		{"resume without token", clientMessage{Type: msgResume}, false},
+		{"stick", clientMessage{Type: msgStick, X: -100, Y: 100}, true},
+		{"stick too far", clientMessage{Type: msgStick, X: 100.5}, false},
+		{"stick NaN", clientMessage{Type: msgStick, Y: math.NaN()}, false},
+		{"button", clientMessage{Type: msgButton, Button: buttonRed}, true},
+		{"unknown button", clientMessage{Type: msgButton, Button: "blue"}, false},
+		{"answer", clientMessage{Type: msgAnswer, Answer: 3}, true},
+		{"answer out of range", clientMessage{Type: msgAnswer, Answer: 4}, false},
+		{"item", clientMessage{Type: msgItem, Item: "mine"}, true},
+		{"unknown item", clientMessage{Type: msgItem, Item: "rocket"}, false},
+		{"ready", clientMessage{Type: msgReady}, true},
+		{"host without PIN", clientMessage{Type: msgHost}, false},
+		{"kick without player", clientMessage{Type: msgKick}, false},
+		{"pack without ID", clientMessage{Type: msgPack}, false},
+		{"playlist", clientMessage{Type: msgPlaylist, Mode: playlistGenerated, Count: 3, Difficulty: "hard"}, true},
+		{"playlist without mode", clientMessage{Type: msgPlaylist}, false},
+		{"unknown type", clientMessage{Type: "dance"}, false},
+	}
+	for _, test := range tests {
+		err := test.msg.validate()
+		if test.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestDecodeLegacyMessage(t *testing.T) {
+	maxHats, maxChars = 3, 2
+
+	tests := []struct {
+		data string
+		want clientMessage
+		ok   bool
+	}{
+		{"NEW 1 2 big bob", clientMessage{Type: msgJoin, Hat: 1, Character: 2, Name: "big bob"}, true},
+		{"NEW 1 2", clientMessage{}, false},
+		{"NEW x 2 bob", clientMessage{}, false},
+		{"NEW 9 2 bob", clientMessage{}, false},
+		{"RESUME abc", clientMessage{Type: msgResume, Token: "abc"}, true},
+		{"RESUME", clientMessage{}, false},
+		{"BAL 50", clientMessage{Type: msgStick, X: 50}, true},
+		{"BAL -20 30", clientMessage{Type: msgStick, X: -20, Y: 30}, true},
+		{"BAL 200", clientMessage{}, false},
+		{"BAL", clientMessage{}, false},
+		{"BTN GREEN", clientMessage{Type: msgButton, Button: buttonGreen}, true},
+		{"BTN BLUE", clientMessage{}, false},
+		{"RSP 2", clientMessage{Type: msgAnswer, Answer: 2}, true},
+		{"RSP 0", clientMessage{}, false},
+		{"", clientMessage{}, false},
+		{"HELLO", clientMessage{}, false},
+	}
+	for _, test := range tests {
+		msg, err := decodeLegacyMessage([]byte(test.data))
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%q: expected an error", test.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.data, err)
+			continue
+		}
+		if msg != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.data, msg, test.want)
+		}
+	}
+}
+
+func TestDecodeInputFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want inputFrame
+		ok   bool
+	}{
+		{"centered", []byte{1, 0, 7, 0, 0, 0}, inputFrame{sequence: 7}, true},
+		{"both buttons", []byte{1, 1, 2, 50, 0xce, inputButtonGreen | inputButtonRed}, inputFrame{sequence: 258, stickX: 50, stickY: -50, buttons: 3}, true},
+		{"edges", []byte{1, 0, 0, 100, 0x9c, 0}, inputFrame{stickX: 100, stickY: -100}, true},
+		{"x too far", []byte{1, 0, 0, 101, 0, 0}, inputFrame{}, false},
+		{"y too far", []byte{1, 0, 0, 0, 0x9b, 0}, inputFrame{}, false},
+		{"short", []byte{1, 0, 0, 0, 0}, inputFrame{}, false},
+		{"long", []byte{1, 0, 0, 0, 0, 0, 0}, inputFrame{}, false},
+		{"empty", nil, inputFrame{}, false},
+		{"unknown kind", []byte{2, 0, 0, 0, 0, 0}, inputFrame{}, false},
+	}
+	for _, test := range tests {
+		frame, err := decodeInputFrame(test.data)
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if frame != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.name, frame, test.want)
+		}
+	}
+}
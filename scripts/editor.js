@@ -0,0 +1,313 @@
+// Level editor for browsers, see webeditor.go for the API
+
+const defaultWidth = 39
+const defaultHeight = 22
+const emptyTile = "/"
+
+let tiles = []
+let packs = []
+let currentPack = ""
+let level = null
+let file = ""
+let selected = 0
+let painting = false
+
+// The spawn tool sits after the tiles in the palette
+const spawnTool = -1
+
+function showMessage(text) {
+    document.getElementById("message").textContent = text
+}
+
+function showProblems(problems) {
+    const list = document.getElementById("problems")
+    list.innerHTML = ""
+    for (const problem of problems) {
+        const item = document.createElement("li")
+        item.textContent = problem
+        list.appendChild(item)
+    }
+}
+
+async function request(method, url, body) {
+    const headers = {}
+    if (body !== undefined) headers["Content-Type"] = "application/json"
+    const pin = document.getElementById("pinInput").value
+    if (pin) headers["X-PIN"] = pin
+
+    const response = await fetch(url, {method, headers, body: body === undefined ? undefined : JSON.stringify(body)})
+    if (!response.ok) throw new Error(await response.text())
+    return response.json()
+}
+
+
+
+
+
+
+//  Palette
+function buildPalette() {
+    const palette = document.getElementById("palette")
+    palette.innerHTML = ""
+
+    tiles.forEach((tile, i) => {
+        const button = document.createElement("div")
+        button.className = "tool"
+        button.title = tile.name
+        if (tile.sprite) button.style.backgroundImage = `url(${tile.sprite})`
+        else button.textContent = tile.name
+        button.onclick = () => selectTool(i)
+        palette.appendChild(button)
+    })
+
+    const spawn = document.createElement("div")
+    spawn.className = "tool"
+    spawn.title = "spawn"
+    spawn.style.backgroundImage = "url(/assets/characters/1_idle.png)"
+    spawn.onclick = () => selectTool(spawnTool)
+    palette.appendChild(spawn)
+
+    selectTool(selected)
+}
+
+function selectTool(i) {
+    selected = i
+    const tools = document.getElementById("palette").children
+    for (let j = 0; j < tools.length; j++) {
+        const index = j < tiles.length ? j : spawnTool
+        tools[j].classList.toggle("selected", index == selected)
+    }
+}
+
+
+
+
+
+
+//  Grid
+// Rows in level.tiles go from the top, x and y here are from the top left
+function setTile(x, y, letter) {
+    const row = level.tiles[y]
+    level.tiles[y] = row.substring(0, x) + letter + row.substring(x + 1)
+}
+
+function drawGrid() {
+    const grid = document.getElementById("grid")
+    grid.innerHTML = ""
+    grid.style.gridTemplateColumns = `repeat(${level.tiles[0].length}, minmax(1.5em, 1fr))`
+
+    const spriteOf = {}
+    for (const tile of tiles) spriteOf[tile.letter] = tile.sprite
+
+    level.tiles.forEach((row, y) => {
+        for (let x = 0; x < row.length; x++) {
+            const cell = document.createElement("div")
+            cell.className = "cell"
+            cell.dataset.x = x
+            cell.dataset.y = y
+            if (spriteOf[row[x]]) cell.style.backgroundImage = `url(${spriteOf[row[x]]})`
+            grid.appendChild(cell)
+        }
+    })
+
+    // Spawns are stored from the bottom left in blocks
+    const height = level.tiles.length
+    for (const spawn of level.spawns) {
+        const x = Math.floor(spawn.x)
+        const y = height - 1 - Math.floor(spawn.y)
+        const cell = grid.querySelector(`[data-x="${x}"][data-y="${y}"]`)
+        if (cell) cell.classList.add("spawn")
+    }
+}
+
+function paint(target, shiftKey) {
+    if (!target || !target.classList.contains("cell")) return
+    const x = Number(target.dataset.x)
+    const y = Number(target.dataset.y)
+
+    if (selected == spawnTool) {
+        const spawn = {x: x + 0.5, y: level.tiles.length - 1 - y + 0.5}
+        if (shiftKey) level.spawns.push(spawn)
+        else level.spawns = [spawn]
+        drawGrid()
+        return
+    }
+
+    const tile = tiles[selected]
+    if (level.tiles[y][x] == tile.letter) return
+    setTile(x, y, tile.letter)
+    target.style.backgroundImage = tile.sprite ? `url(${tile.sprite})` : ""
+}
+
+// Pointer events work for both mice and fingers
+const grid = document.getElementById("grid")
+grid.addEventListener("pointerdown", (event) => {
+    event.preventDefault()
+    painting = selected != spawnTool
+    paint(event.target, event.shiftKey)
+})
+grid.addEventListener("pointermove", (event) => {
+    if (!painting) return
+    paint(document.elementFromPoint(event.clientX, event.clientY), false)
+})
+window.addEventListener("pointerup", () => { painting = false })
+
+
+
+
+
+
+//  Levels
+// Keeps the bottom left corner where it is, like the game counts from there
+function resizeLevel() {
+    const width = Number(document.getElementById("widthInput").value)
+    const height = Number(document.getElementById("heightInput").value)
+    if (!(width >= 1 && height >= 1)) return
+
+    const old = level.tiles
+    const tiles = []
+    for (let row = 0; row < height; row++) {
+        const oldRow = old[old.length - height + row] || ""
+        tiles.push((oldRow + emptyTile.repeat(width)).substring(0, width))
+    }
+    level.tiles = tiles
+    drawGrid()
+}
+
+function readDetails() {
+    level.name = document.getElementById("nameInput").value
+    level.author = document.getElementById("authorInput").value
+    level.timeLimit = Number(document.getElementById("timeInput").value)
+    level.background = Number(document.getElementById("backgroundInput").value)
+    level.triviaCategory = document.getElementById("triviaInput").value
+    const bumping = document.getElementById("bumpingSelect").value
+    if (bumping == "") delete level.bumping
+    else level.bumping = bumping == "on"
+}
+
+function showLevel() {
+    document.getElementById("nameInput").value = level.name
+    document.getElementById("authorInput").value = level.author || ""
+    document.getElementById("timeInput").value = level.timeLimit
+    document.getElementById("backgroundInput").value = level.background
+    document.getElementById("triviaInput").value = level.triviaCategory || ""
+    document.getElementById("bumpingSelect").value = level.bumping === undefined ? "" : level.bumping ? "on" : "off"
+    document.getElementById("fileInput").value = file
+    document.getElementById("widthInput").value = level.tiles[0].length
+    document.getElementById("heightInput").value = level.tiles.length
+    showProblems([])
+    drawGrid()
+}
+
+function showLevelList() {
+    const pack = packs.find((p) => p.id == currentPack)
+    const select = document.getElementById("levelSelect")
+    select.innerHTML = ""
+    for (const name of pack.levels) {
+        const option = document.createElement("option")
+        option.value = name
+        option.textContent = name
+        select.appendChild(option)
+    }
+    select.value = file
+}
+
+// nextFile is the first free numbered file name in the pack
+function nextFile() {
+    const pack = packs.find((p) => p.id == currentPack)
+    let next = 0
+    for (const name of pack.levels) {
+        const n = parseInt(name)
+        if (!isNaN(n) && n >= next) next = n + 1
+    }
+    return `${next}.json`
+}
+
+function choosePack(id) {
+    currentPack = id
+    const pack = packs.find((p) => p.id == id)
+    if (pack.levels.length > 0) openLevel(pack.levels[0])
+    else newLevel()
+}
+
+async function openLevel(name) {
+    try {
+        level = await request("GET", `/api/levels/${currentPack}/${name}`)
+        file = name
+        showLevelList()
+        showLevel()
+        showMessage(`Opened ${name}`)
+    } catch (err) {
+        showMessage(err.message)
+    }
+}
+
+function newLevel() {
+    const width = level ? level.tiles[0].length : defaultWidth
+    const height = level ? level.tiles.length : defaultHeight
+    const pack = packs.find((p) => p.id == currentPack)
+
+    level = {
+        version: 1,
+        name: `Level ${pack.levels.length + 1}`,
+        timeLimit: 0,
+        spawns: [],
+        background: -1,
+        tiles: Array(height).fill(emptyTile.repeat(width)),
+    }
+    file = nextFile()
+    showLevelList()
+    showLevel()
+    showMessage("New level")
+}
+
+async function checkLevel() {
+    readDetails()
+    try {
+        const result = await request("POST", "/api/check", level)
+        showProblems(result.problems)
+        showMessage(result.problems.length == 0 ? "No problems" : `${result.problems.length} problems`)
+    } catch (err) {
+        showMessage(err.message)
+    }
+}
+
+async function saveLevel() {
+    readDetails()
+    file = document.getElementById("fileInput").value.trim()
+    if (!file.endsWith(".json")) file += ".json"
+
+    try {
+        const result = await request("PUT", `/api/levels/${currentPack}/${file}`, level)
+        showProblems(result.problems)
+        showMessage(`Saved ${file}` + (result.problems.length == 0 ? "" : `, ${result.problems.length} problems`))
+
+        packs = await request("GET", "/api/packs")
+        showLevelList()
+    } catch (err) {
+        showMessage(err.message)
+    }
+}
+
+async function start() {
+    try {
+        tiles = await request("GET", "/api/tiles")
+        packs = await request("GET", "/api/packs")
+    } catch (err) {
+        showMessage(err.message)
+        return
+    }
+
+    const select = document.getElementById("packSelect")
+    for (const pack of packs) {
+        const option = document.createElement("option")
+        option.value = pack.id
+        option.textContent = pack.name
+        select.appendChild(option)
+    }
+
+    buildPalette()
+    choosePack(packs[0].id)
+}
+
+start()
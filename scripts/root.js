@@ -1,197 +1,450 @@
-
-// Full Screen
-function toggleFullscreen() {
-    if (!document.fullscreenElement) {
-        document.documentElement.requestFullscreen();
-    } else {
-        if (document.exitFullscreen) {
-            document.exitFullscreen();
-        }
-    }
-}
-
-
-
-
-
-
-//  Hats and Characters
-hat = document.getElementById("hat-img");
-character = document.getElementById("char-img");
-var hat_counter = 1;
-var character_counter = 1;
-
-hat.style.backgroundImage = `url(/assets/hats/${hat_counter}.png)`;
-character.style.backgroundImage = `url(/assets/characters/${character_counter}_idle.png)`;
-
-function hats(modify, maxVal) {
-    if (hat_counter+modify > maxVal || hat_counter+modify == 0) return
-    hat_counter += modify;
-    hat.style.backgroundImage = `url(/assets/hats/${hat_counter}.png)`;
-}
-
-function characters(modify, maxVal) {
-    if (character_counter+modify > maxVal || character_counter+modify == 0) return
-    character_counter += modify;
-    character.style.backgroundImage = `url(/assets/characters/${character_counter}_idle.png)`;
-}
-
-
-
-
-
-
-let socket;
-let playerName
-// Game start
-function togglePrompt(){
-    // Check if player has name
-    playerName = document.getElementById('nameInput').value
-    if (playerName == "") return
-    
-    // Make a new websocket
-    socket = new WebSocket("ws://192.168.100.29:80/ws");
-    socket.addEventListener("open", (event) => {
-        socket.send("NEW " + hat_counter + " " + character_counter + " " + playerName);
-    });
-    
-    p = document.getElementById('prompt')
-    toggleFullscreen()
-    p.style.transition = `margin-top 2s ease-in-out`
-    p.style.marginTop = `100vh`
-    setTimeout(() => {
-        p.style.transition = `unset`;
-    }, 2000);
-    
-    unlockBall()
-}
-
-function unlockBall() {
-    let dragging = false
-    let ball = document.getElementById('ball')
-    
-    const circleCenterY = window.innerHeight*.50;
-    const circleCenterX = window.innerWidth*.25 ;
-    const circleRadius = window.innerHeight*.25;
-    
-    function onStart(event) {
-        if (event.target.tagName.toLowerCase() !== 'button') {
-            dragging = true
-        }
-    }
-
-    function onDrag(event) {
-        if (!dragging) return;
-        let transX =  event.touches[0].clientX - window.innerWidth * 0.25;
-        let transY = event.touches[0].clientY - window.innerHeight * 0.50;
-
-
-        if (Math.sqrt(transX*transX+transY*transY)>window.innerHeight*.25) {
-            let point = closestPointOnCircle(transX, transY, circleCenterX, circleCenterY, circleRadius);
-            transX = point.x
-            transY = point.y
-        }
-
-        ball.style.transform = `translateX(${transX}px) translateY(${transY}px)`;
-        //socket.send("BAL "+ transX/circleRadius*100 + " " + transY/circleRadius*100)
-
-    }
-
-    function onStop(event) {
-        ball.style.transform = `translateX(0) translateY(0)`
-        ball.style.margin = `unset`
-        ball.style.marginTop = circleCenterY
-        ball.style.marginLeft = circleCenterX
-        //socket.send("BAL 0.0 0.0")
-    }
-
-
-    function closestPointOnCircle(transX, transY, circleCenterX, circleCenterY, circleRadius) {
-        const d = Math.sqrt(transX*transX + transY*transY);
-        const ratio = circleRadius / d
-
-
-        const closestPointX = transX*ratio;
-        const closestPointY = transY*ratio;
-    
-        // Return the coordinates of the closest point on the circle
-        return { x: closestPointX, y: closestPointY };
-    }
-
-    document.getElementById('ballCircle').addEventListener('touchstart', onStart);
-    document.getElementById('ballCircle').addEventListener('touchmove', onDrag);
-    document.getElementById('ballCircle').addEventListener('touchend', onStop);
-
-    function getTranslateXY(element) {
-        const style = window.getComputedStyle(element)
-        const matrix = new DOMMatrixReadOnly(style.transform)
-        return {
-            translateX: matrix.m41,
-            translateY: matrix.m42
-        }
-    }
-
-    let bombsLeft = 0
-    let health = 0
-    socket.addEventListener('message', (event) => {
-        let message = event.data
-        if (message.substring(0, 3) == "BOM" && message.split("\\\\")[1] == playerName) {
-            bombsLeft = message.split("\\\\")[2]
-        }
-        
-        if (message.substring(0, 3) == "HEL" && message.split("\\\\")[1] == playerName) {
-            health = message.split("\\\\")[2]
-        }
-
-        if (message.substring(0, 3) == "QUE") {
-            let vals = message.split("\\\\")
-            document.getElementById('question').textContent = vals[1]
-            document.getElementById('response1').textContent = vals[2]
-            document.getElementById('response2').textContent = vals[3]
-            document.getElementById('response3').textContent = vals[4]
-
-            document.getElementById('triviaBox').style.display = `unset`
-        } 
-    });
-
-    requestAnimationFrame(update)
-    function update() {
-        if (!(socket.readyState === WebSocket.OPEN)) {
-            console.log("Socket not ready")
-            requestAnimationFrame(update)
-            return
-        }
-        console.log("Socket ready")
-        let transform = getTranslateXY(ball)
-        socket.send("BAL "+ transform.translateX/circleRadius*100 + " " + transform.translateY/circleRadius*100)
-
-
-        document.getElementById('bombCounter').textContent = bombsLeft
-        document.getElementById('healthBar').style.width = `${health*6}px`
-
-        requestAnimationFrame(update)
-    }
-
-
-}
-
-function greenBTN() {
-    socket.send("BTN GREEN")
-}
-function redBTN() {
-    socket.send("BTN RED")
-}
-
-document.getElementById('response1').addEventListener("touchstart", () => {
-    socket.send("RSP " + 1)
-    document.getElementById('triviaBox').style.display = `none`
-})
-document.getElementById('response2').addEventListener("touchstart", () => {
-    socket.send("RSP " + 2)
-    document.getElementById('triviaBox').style.display = `none`
-})
-document.getElementById('response3').addEventListener("touchstart", () => {
-    socket.send("RSP " + 3)
-    document.getElementById('triviaBox').style.display = `none`
-})
-
+
+// Full Screen
+function toggleFullscreen() {
+    if (!document.fullscreenElement) {
+        document.documentElement.requestFullscreen();
+    } else {
+        if (document.exitFullscreen) {
+            document.exitFullscreen();
+        }
+    }
+}
+
+
+
+
+
+
+//  Hats and Characters
+hat = document.getElementById("hat-img");
+character = document.getElementById("char-img");
+var hat_counter = 1;
+var character_counter = 1;
+
+hat.style.backgroundImage = `url(/assets/hats/${hat_counter}.png)`;
+character.style.backgroundImage = `url(/assets/characters/${character_counter}_idle.png)`;
+
+function hats(modify, maxVal) {
+    if (hat_counter+modify > maxVal || hat_counter+modify == 0) return
+    hat_counter += modify;
+    hat.style.backgroundImage = `url(/assets/hats/${hat_counter}.png)`;
+}
+
+function characters(modify, maxVal) {
+    if (character_counter+modify > maxVal || character_counter+modify == 0) return
+    character_counter += modify;
+    character.style.backgroundImage = `url(/assets/characters/${character_counter}_idle.png)`;
+}
+
+
+
+
+
+
+const protocolVersion = 2
+
+// Binary input frame, see decodeInputFrame in protocol.go
+const inputFrameKind = 1
+const buttonGreen = 1
+const buttonRed = 2
+
+let socket;
+let playerName
+let sessionToken = sessionStorage.getItem("sessionToken")
+// Same order as the game
+const itemNames = ["bomb", "mine", "boost"]
+let items = {}
+let selectedItem = "bomb"
+let health = 0
+let negotiatedVersion = 0
+let inputSequence = 0
+let pressedButtons = 0
+let lastStick = {x: 0, y: 0}
+let myID = 0
+let amReady = false
+let kicked = false
+let gameState = ""
+let gamePaused = false
+let amHost = false
+
+// Connect to the game, picking up our old player if we had one
+function connect() {
+    socket = new WebSocket(`ws://${location.host}/ws`);
+    socket.addEventListener("open", (event) => {
+        send({type: "hello", version: protocolVersion});
+    });
+    socket.addEventListener("message", onMessage);
+    socket.addEventListener("close", (event) => {
+        // Kicked players stay out
+        if (kicked) return
+        // Wi-Fi dropped, try again in a bit
+        setTimeout(connect, 1000);
+    });
+}
+
+function send(message) {
+    if (socket && socket.readyState === WebSocket.OPEN) {
+        socket.send(JSON.stringify(message));
+    }
+}
+
+// Sends the stick and the buttons pressed since the last frame
+function sendInput(x, y) {
+    if (negotiatedVersion < 2) {
+        send({type: "stick", x: x, y: y})
+        return
+    }
+    if (!socket || socket.readyState !== WebSocket.OPEN) return
+
+    inputSequence = (inputSequence + 1) % 65536
+    let frame = new DataView(new ArrayBuffer(6))
+    frame.setUint8(0, inputFrameKind)
+    frame.setUint16(1, inputSequence)
+    frame.setInt8(3, Math.round(x))
+    frame.setInt8(4, Math.round(y))
+    frame.setUint8(5, pressedButtons)
+    socket.send(frame.buffer)
+    pressedButtons = 0
+}
+
+function join() {
+    send({type: "join", name: playerName, hat: hat_counter, character: character_counter});
+}
+
+function onMessage(event) {
+    let message = JSON.parse(event.data)
+    switch (message.type) {
+    case "welcome":
+        negotiatedVersion = message.version
+        // A new connection starts with the stick in the middle
+        lastStick = {x: null, y: null}
+        if (sessionToken) {
+            send({type: "resume", token: sessionToken});
+        } else {
+            join()
+        }
+        break
+
+    case "session":
+        sessionToken = message.token
+        sessionStorage.setItem("sessionToken", sessionToken)
+        myID = message.id
+        break
+
+    case "roster":
+        showRoster(message)
+        break
+
+    case "state":
+        gameState = message.state
+        gamePaused = message.paused
+        showGameState(message)
+        break
+
+    case "error":
+        console.log("Game error: " + message.message)
+        if (message.code == "unknown_session") {
+            // The game forgot about us, join again
+            sessionToken = null
+            sessionStorage.removeItem("sessionToken")
+            join()
+        }
+        if (message.code == "kicked") {
+            kicked = true
+            sessionToken = null
+            sessionStorage.removeItem("sessionToken")
+            document.getElementById('lobbyMessage').textContent = "You were kicked from the game"
+        }
+        if (message.code == "not_host") {
+            document.getElementById('lobbyMessage').textContent = message.message
+        }
+        break
+
+    case "status":
+        items = message.items || {bomb: message.bombs}
+        selectedItem = message.selected || "bomb"
+        health = message.health
+        showInventory()
+        break
+
+    case "question":
+        document.getElementById('question').textContent = message.question
+        document.getElementById('response1').textContent = message.answers[0]
+        document.getElementById('response2').textContent = message.answers[1]
+        document.getElementById('response3').textContent = message.answers[2]
+
+        document.getElementById('triviaBox').style.display = `unset`
+        break
+    }
+}
+
+// What the game is showing right now
+function showGameState(message) {
+    let banner = ""
+    switch (message.state) {
+    case "levelIntro":
+        banner = `Level ${message.level}/${message.levels} - ${message.countdown}`
+        break
+    case "podium":
+        banner = "Podium"
+        break
+    case "results":
+        banner = "Game over!"
+        document.getElementById('triviaBox').style.display = `none`
+        break
+    }
+    if (message.paused) banner = "Paused"
+    document.getElementById('stateBanner').textContent = banner
+    showPauseButton()
+}
+
+// Only the host can pause, and only while a level is on screen
+function showPauseButton() {
+    let inLevel = gameState == "levelIntro" || gameState == "playing" || gameState == "podium"
+    let button = document.getElementById('pauseButton')
+    button.style.display = amHost && inLevel ? `unset` : `none`
+    button.textContent = gamePaused ? "▶" : "II"
+}
+
+function togglePause() {
+    send({type: "pause", paused: !gamePaused})
+}
+
+// Lobby
+function showRoster(message) {
+    document.getElementById('lobby').style.display = message.lobby ? `unset` : `none`
+
+    let me = message.players.find((p) => p.id == myID)
+    amHost = me !== undefined && me.host
+    amReady = me !== undefined && me.ready
+    document.getElementById('readyButton').textContent = amReady ? "Not ready" : "Ready!"
+    document.getElementById('hostControls').style.display = amHost ? `unset` : `none`
+    document.getElementById('pinControls').style.display = amHost ? `none` : `unset`
+    showPauseButton()
+
+    let roster = document.getElementById('roster')
+    roster.replaceChildren()
+    for (const p of message.players) {
+        if (!p.connected) continue
+        let li = document.createElement('li')
+        li.textContent = p.name + (p.host ? " (host)" : "") + (p.ready ? " - ready" : "")
+        if (p.ready) li.className = "ready"
+        if (amHost && p.id != myID) {
+            let kick = document.createElement('button')
+            kick.textContent = "Kick"
+            kick.onclick = () => send({type: "kick", player: p.id})
+            li.appendChild(kick)
+        }
+        roster.appendChild(li)
+    }
+
+    let select = document.getElementById('packSelect')
+    select.replaceChildren()
+    for (const pack of message.packs) {
+        let option = document.createElement('option')
+        option.value = pack.id
+        option.textContent = `${pack.name} (${pack.levels})`
+        option.selected = pack.id == message.pack
+        select.appendChild(option)
+    }
+    document.getElementById('playlistSelect').value = message.playlist
+    let count = document.getElementById('playlistCount')
+    count.value = message.count
+    count.style.display = message.playlist == "random" || message.playlist == "generated" ? `unset` : `none`
+    let difficulty = document.getElementById('difficultySelect')
+    difficulty.value = message.difficulty
+    difficulty.style.display = message.playlist == "generated" ? `unset` : `none`
+    document.getElementById('bumpingInput').checked = message.bumping
+}
+
+function toggleReady() {
+    send({type: "ready", ready: !amReady})
+}
+
+function startGame() {
+    send({type: "start"})
+}
+
+function choosePack(id) {
+    send({type: "pack", pack: id})
+}
+
+function choosePlaylist() {
+    let mode = document.getElementById('playlistSelect').value
+    let count = parseInt(document.getElementById('playlistCount').value) || 1
+    let difficulty = document.getElementById('difficultySelect').value
+    send({type: "playlist", mode: mode, count: count, difficulty: difficulty})
+}
+
+function chooseBumping(on) {
+    send({type: "bumping", bumping: on})
+}
+
+function becomeHost() {
+    let pin = document.getElementById('pinInput').value
+    if (pin == "") return
+    send({type: "host", pin: pin})
+}
+
+// Game start
+function togglePrompt(){
+    // Check if player has name
+    playerName = document.getElementById('nameInput').value
+    if (playerName == "") return
+    
+    // Make a new websocket
+    connect()
+    
+    p = document.getElementById('prompt')
+    toggleFullscreen()
+    p.style.transition = `margin-top 2s ease-in-out`
+    p.style.marginTop = `100vh`
+    setTimeout(() => {
+        p.style.transition = `unset`;
+    }, 2000);
+    
+    unlockBall()
+}
+
+function unlockBall() {
+    let dragging = false
+    let ball = document.getElementById('ball')
+    
+    const circleCenterY = window.innerHeight*.50;
+    const circleCenterX = window.innerWidth*.25 ;
+    const circleRadius = window.innerHeight*.25;
+    
+    function onStart(event) {
+        if (event.target.tagName.toLowerCase() !== 'button') {
+            dragging = true
+        }
+    }
+
+    function onDrag(event) {
+        if (!dragging) return;
+        let transX =  event.touches[0].clientX - window.innerWidth * 0.25;
+        let transY = event.touches[0].clientY - window.innerHeight * 0.50;
+
+
+        if (Math.sqrt(transX*transX+transY*transY)>window.innerHeight*.25) {
+            let point = closestPointOnCircle(transX, transY, circleCenterX, circleCenterY, circleRadius);
+            transX = point.x
+            transY = point.y
+        }
+
+        ball.style.transform = `translateX(${transX}px) translateY(${transY}px)`;
+        //socket.send("BAL "+ transX/circleRadius*100 + " " + transY/circleRadius*100)
+
+    }
+
+    function onStop(event) {
+        ball.style.transform = `translateX(0) translateY(0)`
+        ball.style.margin = `unset`
+        ball.style.marginTop = circleCenterY
+        ball.style.marginLeft = circleCenterX
+        //socket.send("BAL 0.0 0.0")
+    }
+
+
+    function closestPointOnCircle(transX, transY, circleCenterX, circleCenterY, circleRadius) {
+        const d = Math.sqrt(transX*transX + transY*transY);
+        const ratio = circleRadius / d
+
+
+        const closestPointX = transX*ratio;
+        const closestPointY = transY*ratio;
+    
+        // Return the coordinates of the closest point on the circle
+        return { x: closestPointX, y: closestPointY };
+    }
+
+    document.getElementById('ballCircle').addEventListener('touchstart', onStart);
+    document.getElementById('ballCircle').addEventListener('touchmove', onDrag);
+    document.getElementById('ballCircle').addEventListener('touchend', onStop);
+
+    function getTranslateXY(element) {
+        const style = window.getComputedStyle(element)
+        const matrix = new DOMMatrixReadOnly(style.transform)
+        return {
+            translateX: matrix.m41,
+            translateY: matrix.m42
+        }
+    }
+
+    requestAnimationFrame(update)
+    function update() {
+        if (!(socket.readyState === WebSocket.OPEN)) {
+            console.log("Socket not ready")
+            requestAnimationFrame(update)
+            return
+        }
+        console.log("Socket ready")
+        let transform = getTranslateXY(ball)
+        let x = Math.max(-100, Math.min(100, transform.translateX/circleRadius*100))
+        let y = Math.max(-100, Math.min(100, transform.translateY/circleRadius*100))
+        // The game remembers the stick, only tell it about changes
+        if (x != lastStick.x || y != lastStick.y || pressedButtons != 0) {
+            sendInput(x, y)
+            lastStick = {x: x, y: y}
+        }
+
+
+        document.getElementById('bombCounter').textContent = items[selectedItem] || 0
+        document.getElementById('healthBar').style.width = `${health*6}px`
+
+        requestAnimationFrame(update)
+    }
+
+
+}
+
+// Inventory
+// The red button uses the item picked here
+function showInventory() {
+    let inventory = document.getElementById('inventory')
+    if (inventory.children.length == 0) {
+        for (const name of itemNames) {
+            let slot = document.createElement('div')
+            slot.className = 'itemSlot'
+            slot.id = `${name}Slot`
+            slot.style.backgroundImage = `url(/assets/items/${name}.png)`
+            slot.addEventListener("touchstart", () => send({type: "item", item: name}))
+            inventory.appendChild(slot)
+        }
+    }
+    for (const name of itemNames) {
+        let slot = document.getElementById(`${name}Slot`)
+        slot.textContent = items[name] || 0
+        slot.classList.toggle('selected', name == selectedItem)
+        slot.classList.toggle('empty', !items[name])
+    }
+}
+
+function greenBTN() {
+    if (negotiatedVersion < 2) {
+        send({type: "button", button: "green"})
+        return
+    }
+    pressedButtons |= buttonGreen
+}
+function redBTN() {
+    if (negotiatedVersion < 2) {
+        send({type: "button", button: "red"})
+        return
+    }
+    pressedButtons |= buttonRed
+}
+
+document.getElementById('response1').addEventListener("touchstart", () => {
+    send({type: "answer", answer: 1})
+    document.getElementById('triviaBox').style.display = `none`
+})
+document.getElementById('response2').addEventListener("touchstart", () => {
+    send({type: "answer", answer: 2})
+    document.getElementById('triviaBox').style.display = `none`
+})
+document.getElementById('response3').addEventListener("touchstart", () => {
+    send({type: "answer", answer: 3})
+    document.getElementById('triviaBox').style.display = `none`
+})
+
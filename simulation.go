@@ -0,0 +1,104 @@
+package main
+
+import (
+	"time"
+)
+
+const tickRate = 60
+const tickDuration = time.Second / tickRate
+const tickDeltaTime = 1. / tickRate
+const maxTicksPerFrame = 5
+
+// Time simulated by the current level, advanced one tick at a time
+var levelClock time.Duration
+
+// Real time that has not been simulated yet
+var tickAccumulator time.Duration
+
+// playerInput is what a controller asked for since the last tick.
+type playerInput struct {
+	// Up and right are positive
+	stickX float64
+	stickY float64
+	jump   bool
+
+	// Uses the item the goober picked
+	use bool
+}
+
+func applyInputs(deltaTime float64) {
+	for i := range players {
+		if !players[i].connected {
+			players[i].acceleration.X = 0
+			continue
+		}
+		in := players[i].conn.takeInput()
+
+		// Stunned goobers can't do anything until it wears off
+		if players[i].stunLeft > 0 {
+			players[i].acceleration.X = 0
+			continue
+		}
+		applyInput(i, in, deltaTime)
+	}
+}
+
+func applyInput(i int, in playerInput, deltaTime float64) {
+	steerGoober(&players[i], in)
+	if in.use {
+		useItem(i, in)
+	}
+}
+
+// steerGoober applies the stick and the jump button to p.
+func steerGoober(p *player, in playerInput) {
+	// The stick keeps its position until the controller reports a new one
+	speed := p.speed
+	if p.boostLeft > 0 {
+		speed *= boostSpeed
+	}
+	p.acceleration.X = speed * in.stickX
+
+	// gravityHandler jumps once the goober can
+	if in.jump {
+		p.jumpBufferLeft = jumpBufferTime
+	}
+}
+
+// discardInputs drops the buttons pressed while the level was not running.
+// The stick stays where it is.
+func discardInputs() {
+	for i := range players {
+		if players[i].connected {
+			players[i].conn.takeInput()
+		}
+	}
+}
+
+// advanceSimulation runs as many fixed ticks as fit in the time that passed.
+// Leftover time is carried over to the next call so the game runs at the
+// same speed no matter the frame rate.
+func advanceSimulation(elapsed time.Duration) {
+	tickAccumulator += elapsed
+	if tickAccumulator > tickDuration*maxTicksPerFrame {
+		tickAccumulator = tickDuration * maxTicksPerFrame
+	}
+
+	for tickAccumulator >= tickDuration && levelClock < levelDuration {
+		tickAccumulator -= tickDuration
+		simulationStep()
+	}
+}
+
+func simulationStep() {
+	applyInputs(tickDeltaTime)
+	gravityHandler(tickDeltaTime)
+	crumbleBlocks(tickDeltaTime)
+	movementHandler(tickDeltaTime)
+	bumpHandler(tickDeltaTime)
+	itemManager(tickDeltaTime)
+	explosionManager(tickDeltaTime)
+	basicAnimator()
+
+	levelClock += tickDuration
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"main.go/level"
+)
+
+// Levels are built around these, lint-levels and the generator measure
+// them with jumpReach
+func TestJumpReach(t *testing.T) {
+	loadTiles()
+	height, distance := jumpReach()
+	if height != 6 || distance != 27 {
+		t.Errorf("jump goes %d blocks up and %d across, want 6 and 27", height, distance)
+	}
+}
+
+// jumped runs ticks and reports whether goober 0 jumped during them. jumpAt
+// is the tick the jump button is pressed in.
+func jumped(ticks, jumpAt int) bool {
+	for tick := 0; tick < ticks; tick++ {
+		applyInput(0, playerInput{jump: tick == jumpAt}, tickDeltaTime)
+		step(1)
+		if players[0].velocity.Y > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCoyoteTime(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 6; x++ {
+		l.SetTile(x, 4, level.Basic)
+	}
+	testWorld(t, l)
+	blockSizeX, _ := blockSize()
+
+	// Run off the edge and wait a bit before jumping
+	run := func(wait int) bool {
+		players = []player{testGoober(5.5, 5)}
+		step(1)
+		for tick := 0; players[0].grounded; tick++ {
+			if tick > tickRate {
+				t.Fatal("never ran off the edge")
+			}
+			applyInput(0, playerInput{stickX: 100}, tickDeltaTime)
+			step(1)
+		}
+		if hitbox(players[0].position).Min.X < 6*blockSizeX-1 {
+			t.Fatal("fell before the edge")
+		}
+		return jumped(wait+1, wait)
+	}
+
+	coyoteTicks := int(coyoteTime / tickDuration)
+	if !run(coyoteTicks / 2) {
+		t.Errorf("can't jump right after running off the edge")
+	}
+	if run(coyoteTicks + 2) {
+		t.Errorf("jumped in midair long after running off the edge")
+	}
+}
+
+func TestJumpBuffer(t *testing.T) {
+	l := level.New(20, 12)
+	for x := 0; x < 20; x++ {
+		l.SetTile(x, 4, level.Basic)
+	}
+	testWorld(t, l)
+	_, blockSizeY := blockSize()
+
+	// How far a goober falls from standing still in seconds
+	fallFor := func(seconds float64) float64 {
+		return gravity * seconds * seconds / 2
+	}
+	buffer := jumpBufferTime.Seconds()
+
+	// Pressing jump shortly before landing jumps once the goober lands
+	players = []player{testGoober(5.5, 5+fallFor(buffer/2)/blockSizeY)}
+	if !jumped(tickRate, 0) {
+		t.Errorf("jump pressed right before landing was lost")
+	}
+
+	// Pressing it long before does nothing
+	players = []player{testGoober(5.5, 5+fallFor(buffer*2)/blockSizeY)}
+	if jumped(tickRate, 0) {
+		t.Errorf("jump pressed long before landing still happened")
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"main.go/level"
+)
+
+// gameState is the part of the game we are in. The game goroutine moves
+// through them in order:
+//
+//	Story -> Lobby -> LevelIntro -> Playing -> Podium -> LevelIntro ... -> Results -> Lobby
+type gameState int
+
+const (
+	stateStory gameState = iota
+	stateLobby
+	stateLevelIntro
+	statePlaying
+	statePodium
+	stateResults
+
+	// Only reachable from the lobby, by the window
+	stateEditor
+	stateTestPlay
+)
+
+// The level intro counts down from 3 before the players can move
+const levelIntroTime = time.Second * 3
+const resultsDisplayTime = time.Second * 15
+
+var stateNames = map[gameState]string{
+	stateStory:      "story",
+	stateLobby:      "lobby",
+	stateLevelIntro: "levelIntro",
+	statePlaying:    "playing",
+	statePodium:     "podium",
+	stateResults:    "results",
+	stateEditor:     "editor",
+	stateTestPlay:   "testPlay",
+}
+
+func (s gameState) String() string {
+	return stateNames[s]
+}
+
+// stateHooks run when the game enters or leaves a state. update runs every
+// tick while the state is active.
+type stateHooks struct {
+	enter  func()
+	update func(elapsed time.Duration)
+	exit   func()
+}
+
+var stateMachine map[gameState]stateHooks
+
+// Filled in init because the hooks call changeState, which reads the map
+func init() {
+	stateMachine = map[gameState]stateHooks{
+		stateLobby: {
+			enter: enterLobby,
+			update: func(elapsed time.Duration) {
+				//* Everyone in the lobby is ready
+				if allPlayersReady() {
+					startGame()
+				}
+			},
+		},
+		stateLevelIntro: {
+			enter: func() {
+				loadNextLevel()
+				shownCountdown = countdownLeft(stateClock)
+			},
+			update: func(elapsed time.Duration) {
+				if stateClock >= levelIntroTime {
+					changeState(statePlaying)
+					return
+				}
+				if n := countdownLeft(stateClock); n != shownCountdown {
+					shownCountdown = n
+					broadcastState(msgState, newGameStateMessage())
+				}
+			},
+		},
+		statePlaying: {
+			enter: func() {
+				tickAccumulator = 0
+				discardInputs()
+			},
+			update: func(elapsed time.Duration) {
+				advanceSimulation(elapsed)
+				if levelClock >= levelDuration {
+					changeState(statePodium)
+				}
+			},
+		},
+		statePodium: {
+			enter: func() {
+				calculateLevelScore(levelDuration)
+			},
+			update: func(elapsed time.Duration) {
+				//* Podium only stays up for a while
+				if stateClock < podiumDisplayTime {
+					return
+				}
+				if currentLevelID < numOfLevels {
+					changeState(stateLevelIntro)
+				} else {
+					changeState(stateResults)
+				}
+			},
+		},
+		stateResults: {
+			enter: calculateFinalScores,
+			update: func(elapsed time.Duration) {
+				if stateClock >= resultsDisplayTime {
+					changeState(stateLobby)
+				}
+			},
+		},
+		stateTestPlay: {
+			enter: enterTestPlay,
+			update: func(elapsed time.Duration) {
+				advanceSimulation(elapsed)
+				done := levelClock >= levelDuration
+				if i := findPlayerByToken(testPlayerToken); i == -1 || players[i].health <= 0 {
+					done = true
+				}
+				if done {
+					changeState(stateEditor)
+				}
+			},
+			exit: exitTestPlay,
+		},
+	}
+}
+
+var currentState = stateStory
+
+// Time spent in the current state
+var stateClock time.Duration
+
+// Paused games stay in their state but the clocks and the simulation stop
+var paused = false
+
+// Last countdown number the controllers were told about
+var shownCountdown int
+
+// changeState leaves the current state for next and tells the controllers.
+// It must run on the game goroutine.
+func changeState(next gameState) {
+	if hooks := stateMachine[currentState]; hooks.exit != nil {
+		hooks.exit()
+	}
+
+	fmt.Println("State: ", currentState, "->", next)
+	currentState = next
+	stateClock = 0
+	paused = false
+	rosterChanged = true
+
+	if hooks := stateMachine[currentState]; hooks.enter != nil {
+		hooks.enter()
+	}
+	broadcastState(msgState, newGameStateMessage())
+}
+
+func updateState(elapsed time.Duration) {
+	if paused {
+		return
+	}
+	stateClock += elapsed
+	if hooks := stateMachine[currentState]; hooks.update != nil {
+		hooks.update(elapsed)
+	}
+}
+
+// inLobby reports whether players can still get ready and change settings.
+func inLobby() bool {
+	return currentState == stateStory || currentState == stateLobby
+}
+
+// inLevel reports whether there is a level loaded and shown.
+func inLevel() bool {
+	return currentState == stateLevelIntro || currentState == statePlaying || currentState == statePodium
+}
+
+// setPaused pauses or resumes the level that is on screen.
+func setPaused(pause bool) {
+	if !inLevel() || paused == pause {
+		return
+	}
+	paused = pause
+	fmt.Println("Paused: ", paused)
+	if !paused {
+		// Nothing pressed during the pause counts
+		tickAccumulator = 0
+		discardInputs()
+	}
+	broadcastState(msgState, newGameStateMessage())
+}
+
+func togglePause() {
+	setPaused(!paused)
+}
+
+// countdownLeft is the number shown during the level intro, 3, 2, 1 and
+// then 0 once the level starts.
+func countdownLeft(clock time.Duration) int {
+	if clock >= levelIntroTime {
+		return 0
+	}
+	return int((levelIntroTime - clock + time.Second - 1) / time.Second)
+}
+
+func newGameStateMessage() gameStateMessage {
+	msg := gameStateMessage{Type: msgState, State: currentState.String(), Level: currentLevelID, Levels: numOfLevels, Paused: paused}
+	if currentState == stateLevelIntro {
+		msg.Countdown = countdownLeft(stateClock)
+	}
+	return msg
+}
+
+func enterLobby() {
+	currentLevelID = 0
+	for i := range players {
+		players[i].ready = false
+		players[i].score = 0
+	}
+}
+
+// finishStory moves on to the lobby once the story was shown or skipped.
+func finishStory() {
+	if currentState == stateStory {
+		changeState(stateLobby)
+	}
+}
+
+// openEditor switches from the lobby to the level editor and returns it, or
+// nil if the game is not in the lobby.
+func openEditor() *levelEditor {
+	if currentState != stateLobby {
+		return nil
+	}
+	editor := newLevelEditor(currentPack)
+	changeState(stateEditor)
+	return editor
+}
+
+func closeEditor() {
+	if currentState == stateEditor {
+		changeState(stateLobby)
+	}
+}
+
+// Level played from the editor and the keyboard goober playing it
+var testLevel *level.Level
+var testPlayerToken string
+var keyboard = newController(nil)
+
+// Phone goobers sit out test plays here and keep what they have
+var benchedPlayers []player
+
+func startTestPlay(l *level.Level) {
+	if currentState == stateEditor {
+		testLevel = l
+		changeState(stateTestPlay)
+	}
+}
+
+func stopTestPlay() {
+	if currentState == stateTestPlay {
+		changeState(stateEditor)
+	}
+}
+
+func enterTestPlay() {
+	local := newPlayer("Keyboard", 1, 1, keyboard)
+	testPlayerToken = local.token
+	benchedPlayers = players
+	players = []player{local}
+
+	healAllPlayers()
+	clearBlockGrid()
+	applyLevel(testLevel)
+	placeAllPlayers(testLevel.Spawns)
+
+	currentLevel = testLevel
+	levelClock = 0
+	levelDuration = testLevel.Duration()
+	tickAccumulator = 0
+	discardInputs()
+}
+
+func exitTestPlay() {
+	// The keyboard goober goes away, the phones come back
+	players = benchedPlayers
+	benchedPlayers = nil
+	clearBlockGrid()
+	currentLevel = nil
+}
+
+// endLevel cuts the current level short.
+func endLevel() {
+	if currentState == statePlaying {
+		changeState(statePodium)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"main.go/level"
+)
+
+func TestTestPlayBenchesPhones(t *testing.T) {
+	phone := newPlayer("bob", 1, 1, newController(nil))
+	phone.inventory[itemMine] = 2
+	phone.position.X = 123
+	players = []player{phone}
+	t.Cleanup(func() { players = nil })
+
+	testLevel = &level.Level{Version: level.Version, Tiles: []string{"NNN", "N/N", "NNN"}, Spawns: []level.Spawn{{X: 1.5, Y: 1.5}}}
+	enterTestPlay()
+	if len(players) != 1 || players[0].token != testPlayerToken {
+		t.Fatalf("test play has %d goobers, want only the keyboard one", len(players))
+	}
+	exitTestPlay()
+
+	if len(players) != 1 || players[0].token != phone.token {
+		t.Fatalf("got %d goobers back, want the phone one", len(players))
+	}
+	if players[0].inventory != phone.inventory || players[0].position != phone.position {
+		t.Errorf("phone goober changed during the test play")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+
+	"main.go/level"
+)
+
+// tileType is everything the game knows about one kind of block. Blocks in
+// blockGrid point at their type by name.
+type tileType struct {
+	Name string `json:"name"`
+
+	// Letter used in level files
+	Letter string `json:"letter"`
+
+	// Picture in /assets/blocks/
+	Sprite string `json:"sprite"`
+
+	// Solid tiles can be stood on and stop goobers from walking through
+	Solid bool `json:"solid"`
+
+	// Health lost every second on or in the tile
+	Damage float64 `json:"damage,omitempty"`
+
+	// Actions from tileActions, for goobers standing on the tile and for
+	// goobers inside a tile that isn't solid
+	OnStand string `json:"onStand,omitempty"`
+	OnTouch string `json:"onTouch,omitempty"`
+
+	// Share of the friction lost on the tile, from 0 to 1
+	Slide float64 `json:"slide,omitempty"`
+
+	// Speed goobers landing on the tile are launched up with, it can go over
+	// the terminal velocity until they land somewhere else
+	Bounce float64 `json:"bounce,omitempty"`
+
+	// Speed goobers standing on the tile are carried with, negative is left
+	Push float64 `json:"push,omitempty"`
+
+	// Only stops goobers coming from above, they jump through from below
+	// and walk through from the sides
+	OneWay bool `json:"oneWay,omitempty"`
+
+	// Seconds the tile lasts once stood on, 0 lasts forever
+	Crumble float64 `json:"crumble,omitempty"`
+
+	// Explosions destroy the tile
+	Breakable bool `json:"breakable,omitempty"`
+}
+
+// Tiles the game always has, tiles.json can change them or add more
+var builtinTiles = []tileType{
+	{Name: "basic", Letter: string(level.Basic), Sprite: "block.png", Solid: true},
+	{Name: "lava", Letter: string(level.Lava), Sprite: "lava.png", Solid: true, Damage: lavaDamage},
+	{Name: "ability", Letter: string(level.Ability), Sprite: "ability.png", Solid: true, OnStand: "item"},
+	{Name: "finish", Letter: string(level.Finish), Sprite: "finish.png", Solid: true, OnStand: "finish"},
+	{Name: "ice", Letter: string(level.Ice), Sprite: "ice.png", Solid: true, Slide: .9},
+	{Name: "bounce", Letter: string(level.Bounce), Sprite: "bounce.png", Solid: true, Bounce: globalJumpPower * 1.25},
+	{Name: "conveyorLeft", Letter: string(level.ConveyorLeft), Sprite: "conveyor_left.png", Solid: true, Push: -200},
+	{Name: "conveyorRight", Letter: string(level.ConveyorRight), Sprite: "conveyor_right.png", Solid: true, Push: 200},
+	{Name: "oneWay", Letter: string(level.OneWay), Sprite: "oneway.png", Solid: true, OneWay: true},
+	{Name: "crumble", Letter: string(level.Crumble), Sprite: "crumble.png", Solid: true, Crumble: 1},
+	{Name: "cracked", Letter: string(level.Cracked), Sprite: "cracked.png", Solid: true, Breakable: true},
+}
+
+// Optional file next to the game with more tiles, a JSON list of tileType
+const tilesFile = "tiles.json"
+
+// Things a tile can do to player i, x and y are the tile
+var tileActions = map[string]func(i, x, y int){
+	"item":   giveItem,
+	"bomb":   giveBomb,
+	"finish": finishLevel,
+}
+
+// Registered tiles, in the order the editors show them
+var tileTypes []*tileType
+var tilesByName = map[string]*tileType{}
+var tilesByLetter = map[byte]*tileType{}
+
+func loadTiles() {
+	tileTypes = nil
+	tilesByName = map[string]*tileType{}
+	tilesByLetter = map[byte]*tileType{}
+	for _, val := range builtinTiles {
+		if err := registerTile(val); err != nil {
+			panic(err)
+		}
+	}
+
+	data, err := os.ReadFile(path.Join(wd, tilesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	var extra []tileType
+	if err := json.Unmarshal(data, &extra); err != nil {
+		panic(fmt.Errorf("%s: %w", tilesFile, err))
+	}
+	for _, val := range extra {
+		if err := registerTile(val); err != nil {
+			fmt.Println("Failed to add tile: ", err)
+		}
+	}
+}
+
+// registerTile adds t, or changes the tile with the same name.
+func registerTile(t tileType) error {
+	if t.Name == "" {
+		return fmt.Errorf("tile has no name")
+	}
+	if len(t.Letter) != 1 || t.Letter[0] == level.Empty {
+		return fmt.Errorf("tile %s: letter must be one character other than %q", t.Name, level.Empty)
+	}
+	if other, ok := tilesByLetter[t.Letter[0]]; ok && other.Name != t.Name {
+		return fmt.Errorf("tile %s: letter %s is already used by %s", t.Name, t.Letter, other.Name)
+	}
+	for _, action := range []string{t.OnStand, t.OnTouch} {
+		if _, ok := tileActions[action]; action != "" && !ok {
+			return fmt.Errorf("tile %s: unknown action %q", t.Name, action)
+		}
+	}
+
+	if old, ok := tilesByName[t.Name]; ok {
+		delete(tilesByLetter, old.Letter[0])
+		*old = t
+		tilesByLetter[t.Letter[0]] = old
+		return nil
+	}
+	registered := &t
+	tileTypes = append(tileTypes, registered)
+	tilesByName[t.Name] = registered
+	tilesByLetter[t.Letter[0]] = registered
+	return nil
+}
+
+// blockFor turns a level letter into a block, unknown letters are empty.
+func blockFor(letter byte) block {
+	if t, ok := tilesByLetter[letter]; ok {
+		return block{blockType: t.Name}
+	}
+	return block{}
+}
+
+// tileOf returns nil for empty blocks.
+func tileOf(b block) *tileType {
+	return tilesByName[b.blockType]
+}
+
+func isSolid(b block) bool {
+	t := tileOf(b)
+	return t != nil && t.Solid
+}
+
+// isFinish reports whether goobers finish the level on the tile with letter.
+func isFinish(letter byte) bool {
+	t := tilesByLetter[letter]
+	return t != nil && (t.OnStand == "finish" || t.OnTouch == "finish")
+}
+
+// isWall reports whether b stops goobers moving sideways or up.
+func isWall(b block) bool {
+	t := tileOf(b)
+	return t != nil && t.Solid && !t.OneWay
+}
+
+// applyTile runs the damage and the action of t on player i.
+func applyTile(t *tileType, action string, i, x, y int, deltaTime float64) {
+	players[i].health -= t.Damage * deltaTime
+	if action != "" {
+		tileActions[action](i, x, y)
+	}
+}
+
+// standOnTile moves player i the way t does, x and y are the tile.
+func standOnTile(t *tileType, i, x, y int, deltaTime float64) {
+	players[i].terminalVelocity.Y = math.Max(globalTerminalVelocityY, t.Bounce)
+	if t.Bounce > 0 {
+		players[i].velocity.Y = t.Bounce
+		players[i].grounded = false
+		players[i].coyoteLeft = 0
+	}
+	if t.Push != 0 {
+		pushPlayer(i, t.Push*deltaTime)
+	}
+	if t.Crumble > 0 && blockGrid[x][y].crumbleLeft <= 0 {
+		blockGrid[x][y].crumbleLeft = t.Crumble
+		crumblingBlocks = append(crumblingBlocks, struct{ X, Y int }{x, y})
+	}
+}
+
+// pushPlayer moves player i sideways by dx unless a wall is in the way.
+func pushPlayer(i int, dx float64) {
+	movePlayer(i, dx, 0)
+}
+
+// Blocks that have been stood on and are falling apart
+var crumblingBlocks []struct{ X, Y int }
+
+func crumbleBlocks(deltaTime float64) {
+	kept := crumblingBlocks[:0]
+	for _, val := range crumblingBlocks {
+		if val.X >= len(blockGrid) || val.Y >= len(blockGrid[val.X]) {
+			continue
+		}
+		b := &blockGrid[val.X][val.Y]
+		b.crumbleLeft -= deltaTime
+		if b.crumbleLeft <= 0 {
+			*b = block{}
+			continue
+		}
+		kept = append(kept, val)
+	}
+	crumblingBlocks = kept
+}
+
+// gridCell is the block x, y that pos is in.
+func gridCell(pos struct{ X, Y float64 }) (int, int) {
+	blockSizeX, blockSizeY := blockSize()
+	return int(math.Floor(pos.X / blockSizeX)), int(math.Floor(pos.Y / blockSizeY))
+}
+
+func finishLevel(i, x, y int) {
+	players[i].winner = true
+	players[i].health = 0
+	players[i].finishDuration = levelClock
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"main.go/level"
+	"main.go/levelcheck"
+	"main.go/levelgen"
+)
+
+// Tools are run from the command line instead of the game, for example
+// `goobers convert-levels`
+var tools = map[string]func(args []string) error{
+	"convert-levels": convertLevels,
+	"lint-levels":    lintLevels,
+	"generate-level": generateLevel,
+}
+
+// runTool runs the tool called name and returns the exit code.
+func runTool(name string, args []string) int {
+	tool, ok := tools[name]
+	if !ok {
+		fmt.Println("Unknown command: ", name)
+		return 2
+	}
+
+	var err error
+	wd, err = os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+
+	loadTiles()
+
+	if err := tool(args); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// convertLevels turns the old .level files in every pack, or in the packs
+// given as arguments, into level files. The old files are removed.
+func convertLevels(args []string) error {
+	sets := args
+	if len(sets) == 0 {
+		dirs, err := os.ReadDir(path.Join(wd, "/levels/"))
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			if dir.IsDir() {
+				sets = append(sets, dir.Name())
+			}
+		}
+	}
+
+	converted := 0
+	for _, set := range sets {
+		files, err := os.ReadDir(path.Join(wd, "/levels/", set))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if !strings.HasSuffix(file.Name(), level.LegacyExt) {
+				continue
+			}
+			oldPath := path.Join(wd, "/levels/", set, file.Name())
+			id := strings.TrimSuffix(file.Name(), level.LegacyExt)
+
+			data, err := os.ReadFile(oldPath)
+			if err != nil {
+				return err
+			}
+			l, err := level.ParseLegacy(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", oldPath, err)
+			}
+			l.Name = "Level " + id
+			if n, err := strconv.Atoi(id); err == nil {
+				// Players see the levels counted from 1
+				l.Name = fmt.Sprint("Level ", n+1)
+			}
+			if err := l.Save(path.Join(wd, "/levels/", set, id+level.Ext)); err != nil {
+				return err
+			}
+			if err := os.Remove(oldPath); err != nil {
+				return err
+			}
+
+			fmt.Println("Converted", path.Join(set, file.Name()))
+			converted++
+		}
+	}
+
+	fmt.Println("Converted", converted, "levels")
+	return nil
+}
+
+// lintLevels checks every level in every pack, or in the packs given as
+// arguments, and fails if any of them can't be played. Warnings are printed
+// but don't fail. Generated levels are different every game and are left out.
+func lintLevels(args []string) error {
+	sets := args
+	if len(sets) == 0 {
+		dirs, err := os.ReadDir(path.Join(wd, "/levels/"))
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			if dir.IsDir() {
+				sets = append(sets, dir.Name())
+			}
+		}
+	}
+
+	rules := levelRules()
+	fmt.Printf("Jumps go %d blocks up and %d across, lava can be crossed for %d blocks\n", rules.JumpHeight, rules.JumpDistance, rules.DeadlyDistance)
+
+	checked := 0
+	bad := 0
+	for _, set := range sets {
+		p, err := level.LoadPack(path.Join(wd, "/levels/", set))
+		if err != nil {
+			fmt.Println(err)
+			bad++
+			continue
+		}
+		for _, file := range levelFiles(p) {
+			name := path.Join(set, file)
+			checked++
+
+			l, err := p.Load(file)
+			if err != nil {
+				fmt.Println(err)
+				bad++
+				continue
+			}
+			problems := levelcheck.Check(l, rules)
+			for _, val := range problems {
+				fmt.Printf("%s: %s\n", name, val)
+			}
+			if !levelcheck.Playable(problems) {
+				bad++
+			}
+		}
+	}
+
+	fmt.Printf("Checked %d levels, %d with problems\n", checked, bad)
+	if bad > 0 {
+		return fmt.Errorf("%d levels have problems", bad)
+	}
+	return nil
+}
+
+// generateLevel prints a level from the generator, or saves it with -o.
+func generateLevel(args []string) error {
+	flags := flag.NewFlagSet("generate-level", flag.ContinueOnError)
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed for the generator, the same seed makes the same level")
+	difficulty := flags.String("difficulty", defaultDifficulty, "easy, normal or hard")
+	lava := flags.Float64("lava", -1, "chance for a block to be lava, instead of the difficulty's")
+	gap := flags.Int("gap", 0, "widest gap between platforms, instead of the difficulty's")
+	bombs := flags.Int("bombs", -1, "number of ability blocks, instead of the difficulty's")
+	out := flags.String("o", "", "file to save the level to")
+	pack := flags.String("pack", "", "pack in /levels/ to save the level into, -o is the file name")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	d, ok := levelgen.Difficulties[*difficulty]
+	if !ok {
+		return fmt.Errorf("unknown difficulty %q", *difficulty)
+	}
+	if *lava >= 0 {
+		d.LavaDensity = *lava
+	}
+	if *gap > 0 {
+		d.MaxGap = *gap
+	}
+	if *bombs >= 0 {
+		d.Bombs = *bombs
+	}
+
+	l, err := levelgen.Generate(*seed, d, levelRules())
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		data, err := l.Marshal()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if *pack == "" {
+		if err := l.Save(*out); err != nil {
+			return err
+		}
+	} else {
+		p, err := level.LoadPack(path.Join(wd, "/levels/", *pack))
+		if err != nil {
+			return err
+		}
+		if err := l.Save(path.Join(p.Dir, *out)); err != nil {
+			return err
+		}
+		if err := addLevelToPack(p, *out); err != nil {
+			return err
+		}
+	}
+	fmt.Println("Saved", l.Name, "to", *out)
+	return nil
+}
+
+// levelRules describes this game to the level checker.
+func levelRules() levelcheck.Rules {
+	// Levels can be any size, the camera scrolls over the big ones
+	rules := levelcheck.Rules{
+		Tiles: map[byte]levelcheck.Tile{
+			level.Empty: {},
+		},
+	}
+	for _, val := range tileTypes {
+		rules.Tiles[val.Letter[0]] = levelcheck.Tile{Solid: val.Solid, Deadly: val.Damage > 0, OneWay: val.OneWay}
+	}
+
+	_, blockSizeY := blockSize()
+	rules.Floor = int(bottomFloor / blockSizeY)
+	rules.JumpHeight, rules.JumpDistance = jumpReach()
+
+	// Lava burns through all the health in 100/lavaDamage seconds, count on
+	// half the top speed while running over it
+	blockSizeX, _ := blockSize()
+	rules.DeadlyDistance = int(100 / lavaDamage * globalTerminalVelocityX / 2 / blockSizeX)
+	return rules
+}
+
+// jumpReach measures how many blocks a goober gets up and across with a
+// running jump, by running the game physics on a flat level of its own.
+func jumpReach() (int, int) {
+	//* Flat level, standing on the 5th row
+	const ground = 4
+	grid := make([][]block, blocksPerRow)
+	for x := range grid {
+		grid[x] = make([]block, blocksPerCollumn)
+		grid[x][ground].blockType = "basic"
+	}
+	blockSizeX, blockSizeY := blockSize()
+	// Goobers stand with their middle half a block above the top of the
+	// ground, lower and they start inside of it and never land
+	startX := blockSizeX
+	startY := (ground + 1.5) * blockSizeY
+	p := player{
+		position:         struct{ X, Y float64 }{startX, startY},
+		velocity:         struct{ X, Y float64 }{globalTerminalVelocityX, 0},
+		terminalVelocity: struct{ X, Y float64 }{globalTerminalVelocityX, globalTerminalVelocityY},
+		grounded:         true,
+		jumpPower:        globalJumpPower,
+		speed:            gloablSpeed,
+	}
+
+	//* Jump and hold right until back on the ground
+	highest := startY
+	for tick := 0; tick < tickRate*5; tick++ {
+		steerGoober(&p, playerInput{stickX: 100, jump: tick == 0})
+		landGoober(&p, grid)
+		jumpGoober(&p, tickDeltaTime)
+		stepGoober(&p, grid, tickDeltaTime)
+
+		highest = math.Max(highest, p.position.Y)
+		_, _, _, landed := groundUnder(&p, grid)
+		if p.position.X >= blocksPerRow*blockSizeX || (highest > startY && landed) {
+			break
+		}
+	}
+
+	return int((highest - startY) / blockSizeY), int((p.position.X - startX) / blockSizeX)
+}
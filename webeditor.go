@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"main.go/level"
+	"main.go/levelcheck"
+)
+
+// The browser editor talks to these, all under /api/:
+//
+//	GET  /api/tiles               tiles the editor can paint
+//	GET  /api/packs               packs and their levels
+//	GET  /api/levels/<pack>/<file> a level
+//	PUT  /api/levels/<pack>/<file> saves a level, new files are added to the pack
+//	POST /api/check               problems in the level sent
+//
+// Anyone on the network can save new levels. Overwriting a level that is
+// already there needs the host PIN, sent in the X-PIN header, so without
+// -pin the levels the game ships with can't be changed from a browser.
+
+// Level files the editor may write, anything else could leave the pack folder
+var levelFileName = regexp.MustCompile(`^[A-Za-z0-9_-]+\` + level.Ext + `$`)
+
+// Biggest level accepted from a browser
+const maxLevelSize = 1 << 20
+
+type tileInfo struct {
+	Letter string `json:"letter"`
+	Name   string `json:"name"`
+	Sprite string `json:"sprite,omitempty"`
+}
+
+type editorPackInfo struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Levels []string `json:"levels"`
+}
+
+type checkResult struct {
+	Saved    bool     `json:"saved,omitempty"`
+	Problems []string `json:"problems"`
+}
+
+func handleEditor(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, readHTML("editor"))
+}
+
+func handleEditorAPI(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "tiles" && r.Method == http.MethodGet:
+		writeJSON(w, newTileInfos())
+
+	case len(parts) == 1 && parts[0] == "packs" && r.Method == http.MethodGet:
+		var infos []editorPackInfo
+		queryGame(func() {
+			for _, val := range packs {
+				infos = append(infos, editorPackInfo{ID: packID(val), Name: val.Name, Levels: levelFiles(val)})
+			}
+		})
+		writeJSON(w, infos)
+
+	case len(parts) == 1 && parts[0] == "check" && r.Method == http.MethodPost:
+		l, err := readLevel(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, checkResult{Problems: checkLevel(l)})
+
+	case len(parts) == 3 && parts[0] == "levels":
+		pack := findPack(parts[1])
+		if pack == nil {
+			http.Error(w, "unknown level pack", http.StatusNotFound)
+			return
+		}
+		if !levelFileName.MatchString(parts[2]) || parts[2] == level.ManifestName {
+			http.Error(w, "bad level file name", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			getLevel(w, pack, parts[2])
+		case http.MethodPut:
+			putLevel(w, r, pack, parts[2])
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func getLevel(w http.ResponseWriter, pack *level.Pack, file string) {
+	l, err := pack.Load(file)
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "no such level", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, l)
+}
+
+func putLevel(w http.ResponseWriter, r *http.Request, pack *level.Pack, file string) {
+	//* Only the host overwrites levels
+	if _, err := os.Stat(path.Join(pack.Dir, file)); err == nil {
+		if *hostPIN == "" {
+			http.Error(w, "level already exists, start the game with -pin to overwrite levels", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-PIN") != *hostPIN {
+			http.Error(w, "wrong PIN", http.StatusForbidden)
+			return
+		}
+	}
+
+	l, err := readLevel(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := l.Save(path.Join(pack.Dir, file)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	queryGame(func() { err = addLevelToPack(pack, file) })
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Println("Level saved from the browser: ", path.Join(packID(pack), file))
+
+	writeJSON(w, checkResult{Saved: true, Problems: checkLevel(l)})
+}
+
+// readLevel parses the level in the request body with the same checks as
+// level files get.
+func readLevel(w http.ResponseWriter, r *http.Request) (*level.Level, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLevelSize))
+	if err != nil {
+		return nil, err
+	}
+	return level.Parse(data)
+}
+
+func checkLevel(l *level.Level) []string {
+	var rules levelcheck.Rules
+	queryGame(func() { rules = levelRules() })
+
+	problems := []string{}
+	for _, val := range levelcheck.Check(l, rules) {
+		problems = append(problems, val.String())
+	}
+	return problems
+}
+
+func findPack(ID string) *level.Pack {
+	var pack *level.Pack
+	queryGame(func() {
+		for _, val := range packs {
+			if packID(val) == ID {
+				pack = val
+			}
+		}
+	})
+	return pack
+}
+
+func newTileInfos() []tileInfo {
+	var infos []tileInfo
+	queryGame(func() {
+		for _, val := range tileTypes {
+			infos = append(infos, tileInfo{Letter: val.Letter, Name: val.Name, Sprite: "/assets/blocks/" + val.Sprite})
+		}
+	})
+	return append(infos, tileInfo{Letter: string(level.Empty), Name: "eraser"})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Println("Failed to write response: ", err)
+	}
+}